@@ -3,13 +3,35 @@ package auth
 // BCE Common HTTP Headers
 
 const (
-	BCE_PREFIX                  = "x-bce-"
-	BCE_ACL                     = "x-bce-acl"
-	BCE_CONTENT_SHA256          = "x-bce-content-sha256"
-	BCE_COPY_METADATA_DIRECTIVE = "x-bce-metadata-directive"
-	BCE_COPY_SOURCE             = "x-bce-copy-source"
-	BCE_COPY_SOURCE_IF_MATCH    = "x-bce-copy-source-if-match"
-	BCE_DATE                    = "x-bce-date"
-	BCE_USER_METADATA_PREFIX    = "x-bce-meta-"
-	BCE_REQUEST_ID              = "x-bce-request-id"
+	BCE_PREFIX                          = "x-bce-"
+	BCE_ACL                             = "x-bce-acl"
+	BCE_CONTENT_CRC32                   = "x-bce-content-crc32"
+	BCE_CONTENT_SHA256                  = "x-bce-content-sha256"
+	BCE_COPY_METADATA_DIRECTIVE         = "x-bce-metadata-directive"
+	BCE_COPY_SOURCE                     = "x-bce-copy-source"
+	BCE_COPY_SOURCE_IF_MATCH            = "x-bce-copy-source-if-match"
+	BCE_COPY_SOURCE_IF_NONE_MATCH       = "x-bce-copy-source-if-none-match"
+	BCE_COPY_SOURCE_IF_MODIFIED_SINCE   = "x-bce-copy-source-if-modified-since"
+	BCE_COPY_SOURCE_IF_UNMODIFIED_SINCE = "x-bce-copy-source-if-unmodified-since"
+	BCE_COPY_SOURCE_RANGE               = "x-bce-copy-source-range"
+	BCE_DATE                            = "x-bce-date"
+	BCE_FETCH_MODE                      = "x-bce-fetch-mode"
+	BCE_FETCH_SOURCE                    = "x-bce-fetch-source"
+	BCE_NEXT_APPEND_OFFSET              = "x-bce-next-append-offset"
+	BCE_OBJECT_TYPE                     = "x-bce-object-type"
+	BCE_USER_METADATA_PREFIX            = "x-bce-meta-"
+	BCE_REPLICATION_STATUS              = "x-bce-replication-status"
+	BCE_REQUEST_ID                      = "x-bce-request-id"
+	BCE_RESTORE                         = "x-bce-restore"
+	BCE_RESTORE_DAYS                    = "x-bce-restore-days"
+	BCE_RESTORE_TIER                    = "x-bce-restore-tier"
+	BCE_STORAGE_CLASS                   = "x-bce-storage-class"
+
+	BCE_SERVER_SIDE_ENCRYPTION                  = "x-bce-server-side-encryption"
+	BCE_SERVER_SIDE_ENCRYPTION_KMS_KEY_ID       = "x-bce-server-side-encryption-bos-kms-key-id"
+	BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY     = "x-bce-server-side-encryption-customer-key"
+	BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY_MD5 = "x-bce-server-side-encryption-customer-key-md5"
+
+	BCE_COPY_SOURCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY     = "x-bce-copy-source-server-side-encryption-customer-key"
+	BCE_COPY_SOURCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY_MD5 = "x-bce-copy-source-server-side-encryption-customer-key-md5"
 )
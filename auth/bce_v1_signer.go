@@ -69,6 +69,16 @@ func getCannonicalQuery(query string) string {
 func Sign(credentials *BceCredentials, timestamp, httpMethod, path, query string,
 	headers map[string]string) string {
 
+	return SignWithDebug(credentials, timestamp, httpMethod, path, query, headers, Debug)
+}
+
+/*
+ * 同 Sign，debug 为 true 时打印规范请求和签名结果。
+ * 供按客户端设置调试开关的调用方使用，避免并发修改包级变量 Debug
+ */
+func SignWithDebug(credentials *BceCredentials, timestamp, httpMethod, path, query string,
+	headers map[string]string, debug bool) string {
+
 	if path[0] != '/' {
 		path = "/" + path
 	}
@@ -95,11 +105,33 @@ func Sign(credentials *BceCredentials, timestamp, httpMethod, path, query string
 	//fmt.Println(signature)
 
 	authorization := fmt.Sprintf("%s/%s/%s", authStringPrefix, signedHeaders, signature)
-	if Debug {
+	if debug {
 		fmt.Println(CanonicalRequest)
 		fmt.Println(authorization)
 	}
 	return authorization
 }
 
+/*
+ * 生成 PostObject 表单的签名：以 SecretAccessKey 为密钥，对 base64 编码后的 policy
+ * 做 HMAC-SHA256，结果为十六进制字符串
+ */
+func SignPostPolicy(credentials *BceCredentials, policy string) string {
+	return SignPostPolicyWithDebug(credentials, policy, Debug)
+}
+
+/*
+ * 同 SignPostPolicy，debug 为 true 时打印 policy 和签名结果
+ */
+func SignPostPolicyWithDebug(credentials *BceCredentials, policy string, debug bool) string {
+	mac := hmac.New(sha256.New, []byte(credentials.SecretAccessKey))
+	mac.Write([]byte(policy))
+	signature := fmt.Sprintf("%x", mac.Sum(nil))
+	if debug {
+		fmt.Println(policy)
+		fmt.Println(signature)
+	}
+	return signature
+}
+
 /* vim: set expandtab ts=4 sw=4 sts=4 tw=100: */
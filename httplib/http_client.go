@@ -50,6 +50,9 @@ type ErrorResponse struct {
 	Code      string `json:"code"`
 	Message   string `json:"message"`
 	RequestId string `json:"requestId"`
+
+	// StatusCode is the HTTP status of the response.
+	StatusCode int `json:"-"`
 }
 
 func (e *ErrorResponse) Error() string {
@@ -63,19 +66,21 @@ func (c *Client) DoRequest(req *Request) (*http.Response, error) {
 	req.Headers[HOST] = c.GetHost()
 
 	timestamp := utils.GetHttpHeadTimeStamp()
-	auth.Debug = c.Debug
-	authorization := auth.Sign(c.Credential, timestamp, req.Method, req.Path, req.Query, req.Headers)
+	// The client's Debug is passed down with the request rather than written to the
+	// package level switches, which other clients may be reading concurrently.
+	req.Debug = req.Debug || c.Debug
+	authorization := auth.SignWithDebug(c.Credential, timestamp, req.Method, req.Path, req.Query, req.Headers,
+		req.Debug || auth.Debug)
 
 	req.Headers[auth.BCE_DATE] = timestamp
 	req.Headers[AUTHORIZATION] = authorization
 
-	Debug = c.Debug
 	res, err := Run(req, nil)
 	if err != nil {
 		return res, err
 	}
 
-	if res.StatusCode != 200 {
+	if res.StatusCode < 200 || res.StatusCode > 299 {
 		errR := &ErrorResponse{}
 		if req.Method == HEAD || req.Method == DELETE {
 			errR.Code = fmt.Sprintf("%d", res.StatusCode)
@@ -88,7 +93,13 @@ func (c *Client) DoRequest(req *Request) (*http.Response, error) {
 			}
 			j := json.NewDecoder(strings.NewReader(string(body)))
 			j.Decode(&errR)
+			if errR.Code == "" {
+				// Some statuses, such as 304, come without a body.
+				errR.Code = fmt.Sprintf("%d", res.StatusCode)
+				errR.Message = res.Status
+			}
 		}
+		errR.StatusCode = res.StatusCode
 		return res, errR
 	}
 	return res, err
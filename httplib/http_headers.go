@@ -3,6 +3,7 @@ package httplib
 // Standard HTTP Headers
 const (
 	AUTHORIZATION       = "Authorization"
+	CACHE_CONTROL       = "Cache-Control"
 	CONTENT_DISPOSITION = "Content-Disposition"
 	CONTENT_ENCODING    = "Content-Encoding"
 	CONTENT_LENGTH      = "Content-Length"
@@ -13,6 +14,10 @@ const (
 	ETAG                = "ETag"
 	EXPIRES             = "Expires"
 	HOST                = "Host"
+	IF_MATCH            = "If-Match"
+	IF_MODIFIED_SINCE   = "If-Modified-Since"
+	IF_NONE_MATCH       = "If-None-Match"
+	IF_UNMODIFIED_SINCE = "If-Unmodified-Since"
 	LAST_MODIFIED       = "Last-Modified"
 	RANGE               = "Range"
 	SERVER              = "Server"
@@ -1,14 +1,16 @@
 package httplib
 
 import (
-	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"time"
 )
 
+// Debug prints every request and response of the process. Client.Debug turns this on
+// for the requests of one client only.
 var Debug bool
 
 type Request struct {
@@ -18,8 +20,28 @@ type Request struct {
 	Headers map[string]string
 	BaseUrl string
 	Type    string
-	Body    *bytes.Reader
+	Body    io.Reader
 	Timeout time.Duration
+
+	// Debug prints the request and its response, as the package level Debug does for
+	// all requests.
+	Debug bool
+
+	// ContentLength is the number of bytes Body will yield. When zero it is taken from
+	// Body's Len method (bytes.Reader, bytes.Buffer, strings.Reader), if it has one.
+	ContentLength int64
+}
+
+func (req *Request) contentLength() int64 {
+	if req.ContentLength > 0 {
+		return req.ContentLength
+	}
+	if l, ok := req.Body.(interface {
+		Len() int
+	}); ok {
+		return int64(l.Len())
+	}
+	return 0
 }
 
 func (req *Request) url() (*url.URL, error) {
@@ -40,7 +62,7 @@ func initHttpRequest(req *Request) (*http.Request, error) {
 	}
 	if req.Body != nil {
 		newReq.Body = ioutil.NopCloser(req.Body)
-		newReq.ContentLength = int64(req.Body.Len())
+		newReq.ContentLength = req.contentLength()
 		newReq.Header.Add(CONTENT_LENGTH, fmt.Sprintf("%d", newReq.ContentLength))
 		if req.Type != "" {
 			newReq.Header.Add(CONTENT_TYPE, req.Type)
@@ -51,9 +73,9 @@ func initHttpRequest(req *Request) (*http.Request, error) {
 	return newReq, nil
 }
 
-func doHttpRequest(httpClient *http.Client, req *http.Request, res interface{}) (*http.Response, error) {
+func doHttpRequest(httpClient *http.Client, req *http.Request, res interface{}, debug bool) (*http.Response, error) {
 	result, err := httpClient.Do(req)
-	if Debug {
+	if debug {
 		fmt.Println("+++++++++++++++++++++++++++++++")
 		fmt.Println(req)
 		fmt.Println("-------------------------------")
@@ -71,5 +93,5 @@ func Run(req *Request, res interface{}) (*http.Response, error) {
 		Timeout: req.Timeout,
 	}
 
-	return doHttpRequest(httpClient, hreq, res)
+	return doHttpRequest(httpClient, hreq, res, Debug || req.Debug)
 }
@@ -0,0 +1,284 @@
+package bos
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+// Object types reported in ObjectMeta.ObjectType.
+const (
+	ObjectTypeNormal     = "Normal"
+	ObjectTypeAppendable = "Appendable"
+)
+
+/*
+ * Name: AppendObject
+ * URL: http://bce.baidu.com/doc/BOS/API.html#AppendObject.E6.8E.A5.E5.8F.A3
+ */
+
+type AppendObjectResponse struct {
+	ETag string
+
+	// NextAppendOffset is the offset the next append must be made at, the size of the
+	// object so far.
+	NextAppendOffset int64
+	ContentCRC32     string
+}
+
+// AppendObject adds body to the end of an appendable object, or creates one when offset
+// is 0. offset must be the current size of the object, as returned by the previous
+// append; otherwise BOS refuses the append with OffsetIncorrect.
+func (c *BosClient) AppendObject(bucketName, objectName string, offset int64, body io.Reader) (*AppendObjectResponse, error) {
+	return c.AppendObjectWithOptions(bucketName, objectName, offset, body, nil)
+}
+
+// AppendObjectWithOptions is AppendObject with the headers of the object described by
+// opts. They only apply to the append creating the object, at offset 0.
+func (c *BosClient) AppendObjectWithOptions(bucketName, objectName string, offset int64, body io.Reader,
+	opts *PutObjectOptions) (output *AppendObjectResponse, err error) {
+
+	if opts == nil {
+		opts = &PutObjectOptions{}
+	}
+	if body == nil {
+		body = bytes.NewReader(nil)
+	}
+
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method:  httplib.POST,
+		Headers: map[string]string{},
+		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
+		Query:   "append&offset=" + strconv.FormatInt(offset, 10),
+	}
+
+	body, size, err := sizedBody(body, opts.ContentLength)
+	if err != nil {
+		return nil, err
+	}
+	if offset == 0 {
+		req.Type = opts.ContentType
+		if req.Type == "" {
+			req.Type, body = detectContentType(objectName, body)
+		}
+		opts.setHeaders(req.Headers)
+	}
+	if opts.ContentMD5 != "" {
+		req.Headers[httplib.CONTENT_MD5] = opts.ContentMD5
+	}
+	if opts.ContentCRC32 != "" {
+		req.Headers[auth.BCE_CONTENT_CRC32] = opts.ContentCRC32
+	}
+	req.Body = body
+	req.ContentLength = size
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+
+	next, err := strconv.ParseInt(res.Header.Get(auth.BCE_NEXT_APPEND_OFFSET), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad %s %q", auth.BCE_NEXT_APPEND_OFFSET, res.Header.Get(auth.BCE_NEXT_APPEND_OFFSET))
+	}
+	return &AppendObjectResponse{
+		ETag:             strings.Trim(res.Header.Get("ETag"), "\""),
+		NextAppendOffset: next,
+		ContentCRC32:     res.Header.Get(auth.BCE_CONTENT_CRC32),
+	}, nil
+}
+
+const DefaultAppendChunkSize = 1024 * 1024
+
+// AppendWriter writes an appendable object, such as a live recording, as it is
+// produced. Writes are buffered and appended ChunkSize bytes at a time; Flush appends
+// what is buffered at once, and Close appends the rest and finalizes the writer.
+//
+// A failed append is retried. When a retry finds the object longer than expected, the
+// earlier attempt may have landed without its answer arriving: the writer checks the
+// end of the object against what it sent, and carries on if they are the same. An
+// object changed by anyone else fails the writer.
+//
+// An AppendWriter is not safe for concurrent use.
+type AppendWriter struct {
+	// ChunkSize is how many bytes are buffered before they are appended.
+	ChunkSize int
+
+	// MaxRetries is how many times a failed append is retried.
+	MaxRetries int
+
+	client     *BosClient
+	bucketName string
+	objectName string
+	opts       PutObjectOptions
+
+	// checkMD5 and checkCRC32 send the digests of each chunk, when the options the
+	// writer was made with asked for them.
+	checkMD5   bool
+	checkCRC32 bool
+
+	offset int64
+	crc32  string
+	buf    []byte
+	err    error
+	closed bool
+}
+
+// NewAppendWriter returns a writer appending to bucketName/objectName from offset on.
+// Pass 0 to create the object, with the headers described by opts, or the
+// NextAppendOffset of its ObjectMeta to continue an existing one.
+//
+// ContentLength, ContentMD5 and ContentCRC32 in opts do not describe a single append:
+// they are dropped, and setting ContentMD5 or ContentCRC32 has the digest of each
+// chunk sent with it instead.
+func NewAppendWriter(c *BosClient, bucketName, objectName string, offset int64, opts *PutObjectOptions) *AppendWriter {
+	w := &AppendWriter{
+		ChunkSize:  DefaultAppendChunkSize,
+		MaxRetries: DefaultPartRetries,
+		client:     c,
+		bucketName: bucketName,
+		objectName: c.formatPath(objectName),
+		offset:     offset,
+	}
+	if opts != nil {
+		w.opts = *opts
+		w.checkMD5, w.checkCRC32 = opts.ContentMD5 != "", opts.ContentCRC32 != ""
+		w.opts.ContentLength, w.opts.ContentMD5, w.opts.ContentCRC32 = 0, "", ""
+	}
+	return w
+}
+
+// Offset returns the size of the object once everything written so far is appended.
+func (w *AppendWriter) Offset() int64 {
+	return w.offset + int64(len(w.buf))
+}
+
+// ContentCRC32 returns the CRC32 BOS reported for the last append.
+func (w *AppendWriter) ContentCRC32() string {
+	return w.crc32
+}
+
+func (w *AppendWriter) chunkSize() int {
+	if w.ChunkSize <= 0 {
+		return DefaultAppendChunkSize
+	}
+	return w.ChunkSize
+}
+
+func (w *AppendWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fmt.Errorf("append to %s/%s: writer is closed", w.bucketName, w.objectName)
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= w.chunkSize() {
+		if err := w.appendChunk(w.buf[:w.chunkSize()]); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush appends everything buffered.
+func (w *AppendWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	return w.appendChunk(w.buf)
+}
+
+// Close flushes the writer. Later writes fail; the object can still be continued by a
+// new writer at Offset.
+func (w *AppendWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	return w.Flush()
+}
+
+// appendChunk appends chunk, a prefix of the buffer, and drops it from the buffer.
+// Failures are sticky.
+func (w *AppendWriter) appendChunk(chunk []byte) (err error) {
+	opts := w.opts
+	if w.checkMD5 {
+		sum := md5.Sum(chunk)
+		opts.ContentMD5 = base64.StdEncoding.EncodeToString(sum[:])
+	}
+	if w.checkCRC32 {
+		opts.ContentCRC32 = strconv.FormatUint(uint64(crc32.ChecksumIEEE(chunk)), 10)
+	}
+
+	for attempt := 0; attempt <= w.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt*attempt) * 200 * time.Millisecond)
+		}
+
+		var res *AppendObjectResponse
+		res, err = w.client.AppendObjectWithOptions(w.bucketName, w.objectName, w.offset, bytes.NewReader(chunk), &opts)
+		if err == nil {
+			w.offset, w.crc32 = res.NextAppendOffset, res.ContentCRC32
+			break
+		}
+		e, ok := err.(*httplib.ErrorResponse)
+		if ok && e.Code == "OffsetIncorrect" {
+			err = w.recover(chunk)
+			break
+		}
+		if ok && e.StatusCode < 500 {
+			break
+		}
+	}
+	if err != nil {
+		w.err = fmt.Errorf("append to %s/%s at offset %d: %v", w.bucketName, w.objectName, w.offset, err)
+		return w.err
+	}
+	w.buf = append(w.buf[:0], w.buf[len(chunk):]...)
+	return nil
+}
+
+// recover handles an append refused for its offset, succeeding if the object already
+// ends with chunk at the writer's offset.
+func (w *AppendWriter) recover(chunk []byte) error {
+	meta, err := w.client.GetObjectMeta(w.bucketName, w.objectName)
+	if err != nil {
+		return err
+	}
+	end := w.offset + int64(len(chunk))
+	if meta.NextAppendOffset != end {
+		return fmt.Errorf("object is %d bytes, changed by another writer", meta.NextAppendOffset)
+	}
+
+	res, err := w.client.GetObjectWithOptions(w.bucketName, w.objectName,
+		&GetObjectOptions{Range: &ObjectRange{Start: w.offset, End: end - 1}})
+	if err != nil {
+		return err
+	}
+	tail, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(tail, chunk) {
+		return fmt.Errorf("object was appended to by another writer")
+	}
+	w.offset, w.crc32 = end, meta.ContentCRC32
+	return nil
+}
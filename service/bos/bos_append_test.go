@@ -0,0 +1,171 @@
+package bos
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+)
+
+func TestAppendObject(t *testing.T) {
+	f, c := newFakeBos(t)
+
+	res, err := c.AppendObject(TestBukketName, "live.ts", 0, strings.NewReader("first"))
+	if err != nil || res.NextAppendOffset != 5 {
+		t.Fatalf("AppendObject failed. %v %+v", err, res)
+	}
+	res, err = c.AppendObject(TestBukketName, "live.ts", res.NextAppendOffset, strings.NewReader("-second"))
+	if err != nil || res.NextAppendOffset != 12 {
+		t.Fatalf("AppendObject failed. %v %+v", err, res)
+	}
+	if want := fmt.Sprint(crc32.ChecksumIEEE([]byte("first-second"))); res.ContentCRC32 != want {
+		t.Errorf("AppendObject returned CRC32 %s, want %s", res.ContentCRC32, want)
+	}
+	if obj := f.object(TestBukketName, "live.ts"); string(obj.data) != "first-second" ||
+		obj.header.Get("Content-Type") != "video/mp2t" {
+		t.Errorf("AppendObject stored %q, %v", obj.data, obj.header)
+	}
+
+	meta, err := c.GetObjectMeta(TestBukketName, "live.ts")
+	if err != nil || meta.ObjectType != ObjectTypeAppendable || meta.NextAppendOffset != 12 {
+		t.Errorf("GetObjectMeta failed. %v %+v", err, meta)
+	}
+
+	if _, err = c.AppendObject(TestBukketName, "live.ts", 5, strings.NewReader("again")); err == nil {
+		t.Errorf("AppendObject should fail at the wrong offset")
+	}
+	f.putObject(TestBukketName, "normal.ts", []byte("normal"), nil)
+	if _, err = c.AppendObject(TestBukketName, "normal.ts", 6, strings.NewReader("more")); err == nil {
+		t.Errorf("AppendObject should fail on a normal object")
+	}
+}
+
+func appendRequests(f *fakeBos) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, r := range f.requests {
+		if _, ok := r.URL.Query()["append"]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+func TestAppendWriter(t *testing.T) {
+	f, c := newFakeBos(t)
+
+	w := NewAppendWriter(c, TestBukketName, "live.ts", 0, &PutObjectOptions{UserMeta: map[string]string{"channel": "7"}})
+	w.ChunkSize = 10
+	var want bytes.Buffer
+	for i := 0; i < 7; i++ {
+		line := fmt.Sprintf("packet %d\n", i)
+		want.WriteString(line)
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed. %v", err)
+		}
+	}
+	if n := appendRequests(f); n != want.Len()/10 {
+		t.Errorf("AppendWriter sent %d appends before Close, want %d", n, want.Len()/10)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed. %v", err)
+	}
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Errorf("Write should fail after Close")
+	}
+
+	obj := f.object(TestBukketName, "live.ts")
+	if !bytes.Equal(obj.data, want.Bytes()) || w.Offset() != int64(want.Len()) || obj.header.Get("X-Bce-Meta-Channel") != "7" {
+		t.Errorf("AppendWriter stored %q at offset %d, %v", obj.data, w.Offset(), obj.header)
+	}
+	if w.ContentCRC32() != fmt.Sprint(crc32.ChecksumIEEE(want.Bytes())) {
+		t.Errorf("AppendWriter reported CRC32 %s", w.ContentCRC32())
+	}
+
+	// A writer continuing the object picks up where the last one stopped.
+	w = NewAppendWriter(c, TestBukketName, "live.ts", w.Offset(), nil)
+	w.Write([]byte("tail"))
+	if err := w.Close(); err != nil || string(f.object(TestBukketName, "live.ts").data) != want.String()+"tail" {
+		t.Errorf("Close of the continuing writer failed. %v", err)
+	}
+}
+
+func TestAppendWriterObjectDigests(t *testing.T) {
+	f, c := newFakeBos(t)
+	content := randomContent(t, 35)
+	sum := md5.Sum(content)
+
+	// Options describing the whole object must not be applied to each chunk.
+	w := NewAppendWriter(c, TestBukketName, "live.ts", 0, &PutObjectOptions{
+		ContentLength: int64(len(content)),
+		ContentMD5:    base64.StdEncoding.EncodeToString(sum[:]),
+		ContentCRC32:  fmt.Sprint(crc32.ChecksumIEEE(content)),
+	})
+	w.ChunkSize = 10
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("Write failed. %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed. %v", err)
+	}
+	if n := appendRequests(f); n != 4 {
+		t.Errorf("AppendWriter sent %d appends, want 4", n)
+	}
+	if obj := f.object(TestBukketName, "live.ts"); !bytes.Equal(obj.data, content) {
+		t.Errorf("AppendWriter stored %d bytes, want %d", len(obj.data), len(content))
+	}
+	last := f.lastRequest(http.MethodPost)
+	chunkSum := md5.Sum(content[30:])
+	if last.Header.Get("Content-Md5") != base64.StdEncoding.EncodeToString(chunkSum[:]) ||
+		last.Header.Get(auth.BCE_CONTENT_CRC32) != fmt.Sprint(crc32.ChecksumIEEE(content[30:])) {
+		t.Errorf("last append sent digests %v", last.Header)
+	}
+}
+
+func TestAppendWriterRecovery(t *testing.T) {
+	f, c := newFakeBos(t)
+
+	// The first append fails with a server error and is retried.
+	failed := false
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if _, ok := r.URL.Query()["append"]; ok && !failed {
+			failed = true
+			f.fail(w, http.StatusInternalServerError, "InternalError", "try again")
+			return true
+		}
+		return false
+	}
+	w := NewAppendWriter(c, TestBukketName, "live.ts", 0, nil)
+	w.Write([]byte("one"))
+	if err := w.Flush(); err != nil || string(f.object(TestBukketName, "live.ts").data) != "one" {
+		t.Fatalf("Flush did not retry the append. %v", err)
+	}
+	f.intercept = nil
+
+	// An append that landed without the writer hearing of it is recognized.
+	c.AppendObject(TestBukketName, "live.ts", 3, strings.NewReader("two"))
+	w.Write([]byte("two"))
+	if err := w.Flush(); err != nil || w.Offset() != 6 {
+		t.Fatalf("Flush did not recover the landed append. %v", err)
+	}
+	if data := f.object(TestBukketName, "live.ts").data; string(data) != "onetwo" {
+		t.Errorf("object holds %q", data)
+	}
+
+	// Data appended by someone else fails the writer.
+	c.AppendObject(TestBukketName, "live.ts", 6, strings.NewReader("xyz"))
+	w.Write([]byte("abc"))
+	if err := w.Close(); err == nil {
+		t.Errorf("Close should fail after another writer appended")
+	}
+	if _, err := w.Write([]byte("more")); err == nil {
+		t.Errorf("Write should keep failing")
+	}
+}
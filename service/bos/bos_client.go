@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spiderorg/bd-video-sdk/auth"
 	"github.com/spiderorg/bd-video-sdk/httplib"
@@ -148,41 +151,72 @@ func (c *BosClient) PutBucket(bucketName string) (err error) {
  * URL: http://bce.baidu.com/doc/BOS/API.html#GetBucket.2FListObjects.E6.8E.A5.E5.8F.A3
  */
 
+// ObjectInfo describes an object in a listing. Listings only carry the size, ETag,
+// modification time and storage class of ObjectMeta.
 type ObjectInfo struct {
-	ObjectName   string `json:"key"`
-	LastModified string
-	ETag         string
-	Size         int64
-	Owner        OwnerInfo
+	ObjectName string `json:"key"`
+	ObjectMeta
+	Owner OwnerInfo
+}
+
+// PrefixInfo is a common prefix, the equivalent of a directory when listing with a
+// delimiter.
+type PrefixInfo struct {
+	Prefix string `json:"prefix"`
 }
 
 type ListObjectsResponse struct {
-	Name        string
-	Prefix      string
-	Delimiter   string
-	Marker      string
-	MaxKeys     int
-	IsTruncated bool
-	Contents    []ObjectInfo
+	Name           string
+	Prefix         string
+	Delimiter      string
+	Marker         string
+	NextMarker     string
+	MaxKeys        int
+	IsTruncated    bool
+	Contents       []ObjectInfo
+	CommonPrefixes []PrefixInfo
 }
 
-// TODO: Need test
+// ListObjects lists one page of objects. Each of delimiter, marker, maxKeys and prefix
+// may be nil to leave it out; see ListObjectsWithArgs for a typed form.
 func (c *BosClient) ListObjects(bucketName string,
 	delimiter, marker, maxKeys, prefix interface{}) (output *ListObjectsResponse, err error) {
 
+	args := &ListObjectsArgs{
+		Delimiter: stringArg(delimiter),
+		Marker:    stringArg(marker),
+		Prefix:    stringArg(prefix),
+	}
+	if args.MaxKeys, err = intArg(maxKeys); err != nil {
+		return nil, err
+	}
+	return c.ListObjectsWithArgs(bucketName, args)
+}
+
+type ListObjectsArgs struct {
+	Delimiter string
+	Marker    string
+	MaxKeys   int
+	Prefix    string
+}
+
+func (c *BosClient) ListObjectsWithArgs(bucketName string, args *ListObjectsArgs) (output *ListObjectsResponse, err error) {
+	if args == nil {
+		args = &ListObjectsArgs{}
+	}
+
 	query := []string{}
-	if delimiter != nil {
-		query = append(query, "delimiter="+delimiter.(string))
+	if args.Delimiter != "" {
+		query = append(query, "delimiter="+queryEscape(args.Delimiter))
 	}
-	if marker != nil {
-		query = append(query, "marker="+marker.(string))
+	if args.Marker != "" {
+		query = append(query, "marker="+queryEscape(args.Marker))
 	}
-	if maxKeys != nil {
-		query = append(query, "maxKeys="+maxKeys.(string))
+	if args.MaxKeys > 0 {
+		query = append(query, "maxKeys="+strconv.Itoa(args.MaxKeys))
 	}
-	if prefix != nil {
-		prefix = c.formatPath(prefix.(string))
-		query = append(query, "prefix="+prefix.(string))
+	if args.Prefix != "" {
+		query = append(query, "prefix="+queryEscape(c.formatPath(args.Prefix)))
 	}
 	req := &httplib.Request{
 		Method:  httplib.GET,
@@ -208,6 +242,50 @@ func (c *BosClient) ListObjects(bucketName string,
 	return &response, nil
 }
 
+// DirectoryListing is the content of one level of a bucket, as if "/" separated
+// directories.
+type DirectoryListing struct {
+	Prefix string
+
+	// Prefixes holds the sub-directories, each ending in "/".
+	Prefixes []string
+	Objects  []ObjectInfo
+}
+
+// ListDirectory lists the objects and sub-directories directly under prefix, following
+// the listing across as many pages as it takes. A prefix that does not end in "/" is
+// treated as if it did; an empty prefix lists the top of the bucket. The placeholder
+// object some tools create to stand for the directory itself is left out.
+func (c *BosClient) ListDirectory(bucketName, prefix string) (*DirectoryListing, error) {
+	prefix = c.formatPath(prefix)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	listing := &DirectoryListing{Prefix: prefix}
+
+	args := &ListObjectsArgs{Prefix: prefix, Delimiter: "/"}
+	for {
+		page, err := c.ListObjectsWithArgs(bucketName, args)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page.CommonPrefixes {
+			listing.Prefixes = append(listing.Prefixes, p.Prefix)
+		}
+		for _, obj := range page.Contents {
+			if obj.ObjectName != prefix {
+				listing.Objects = append(listing.Objects, obj)
+			}
+		}
+
+		marker := nextObjectMarker(page)
+		if !page.IsTruncated || marker == "" || marker == args.Marker {
+			return listing, nil
+		}
+		args.Marker = marker
+	}
+}
+
 /*
  * Name: HeadBucket
  * URL: http://bce.baidu.com/doc/BOS/API.html#HeadBucket.E6.8E.A5.E5.8F.A3
@@ -250,13 +328,55 @@ func (c *BosClient) DeleteBucket(bucketName string) (err error) {
  * URL: http://bce.baidu.com/doc/BOS/API.html#GetBucketAcl.E6.8E.A5.E5.8F.A3-1
  */
 
+// Canned ACLs, for SetBucketAcl, SetObjectAcl and PutObjectOptions.CannedAcl.
+const (
+	CannedAclPrivate         = "private"
+	CannedAclPublicRead      = "public-read"
+	CannedAclPublicReadWrite = "public-read-write"
+)
+
+// Permissions granted by a GranteeGroup.
+const (
+	PermissionRead        = "READ"
+	PermissionWrite       = "WRITE"
+	PermissionList        = "LIST"
+	PermissionFullControl = "FULL_CONTROL"
+)
+
+// GranteeAllUsers is the grantee ID that stands for everyone, signed in or not.
+const GranteeAllUsers = "*"
+
 type GranteeInfo struct {
-	Id string
+	Id string `json:"id"`
 }
 
+// RefererCondition limits a grant to requests whose Referer matches one of StringLike,
+// in which * is a wildcard, or equals one of StringEquals.
+type RefererCondition struct {
+	StringLike   []string `json:"stringLike,omitempty"`
+	StringEquals []string `json:"stringEquals,omitempty"`
+}
+
+// AclCondition limits a grant to some requests. IpAddress holds addresses or CIDR
+// blocks, such as "192.168.0.0/16".
+type AclCondition struct {
+	Referer   *RefererCondition `json:"referer,omitempty"`
+	IpAddress []string          `json:"ipAddress,omitempty"`
+}
+
+// Effects of a GranteeGroup. A grant denying a permission wins over any allowing it.
+const (
+	AclEffectAllow = "Allow"
+	AclEffectDeny  = "Deny"
+)
+
 type GranteeGroup struct {
-	Grantee    []GranteeInfo
-	Permission []string
+	Grantee    []GranteeInfo `json:"grantee"`
+	Permission []string      `json:"permission"`
+	Condition  *AclCondition `json:"condition,omitempty"`
+
+	// Effect is AclEffectAllow when empty.
+	Effect string `json:"effect,omitempty"`
 }
 
 type BucketAclResponse struct {
@@ -294,7 +414,6 @@ func (c *BosClient) GetBucketAcl(bucketName string) (output *BucketAclResponse,
  * URL: http://bce.baidu.com/doc/BOS/API.html#SetBucketAcl.E6.8E.A5.E5.8F.A3
  */
 
-// TODO: Upload ACL filed not implement
 func (c *BosClient) SetBucketAcl(bucketName string, cannedAcl string) (err error) {
 	req := &httplib.Request{
 		Method:  httplib.PUT,
@@ -307,6 +426,35 @@ func (c *BosClient) SetBucketAcl(bucketName string, cannedAcl string) (err error
 	return
 }
 
+// SetBucketAclWithGrants replaces the ACL of a bucket with the given grants. Grants
+// replace the owner's implicit full control too, so include it when it is still needed.
+func (c *BosClient) SetBucketAclWithGrants(bucketName string, grants []GranteeGroup) (err error) {
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{},
+		Query:   "acl",
+		Path:    c.APIVersion + "/" + bucketName,
+		Type:    httplib.JSON,
+	}
+	if req.Body, err = aclBody(grants); err != nil {
+		return
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+func aclBody(grants []GranteeGroup) (io.Reader, error) {
+	if len(grants) == 0 {
+		return nil, fmt.Errorf("an ACL needs at least one grant")
+	}
+	jstring, err := json.Marshal(map[string][]GranteeGroup{"accessControlList": grants})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(jstring), nil
+}
+
 /*************************************************************************************************
 
 Object Operation Method
@@ -321,6 +469,82 @@ Object Operation Method
 func (c *BosClient) PutObject(bucketName, objectName string, body *bytes.Reader,
 	contentMD5, contentSHA256 string, metaInfo map[string]string) (eTag string, err error) {
 
+	return c.PutObjectWithOptions(bucketName, objectName, body, &PutObjectOptions{
+		ContentType:   httplib.OCTET_STREAM,
+		ContentMD5:    contentMD5,
+		ContentSHA256: contentSHA256,
+		UserMeta:      metaInfo,
+	})
+}
+
+// Storage classes accepted by x-bce-storage-class.
+const (
+	StorageClassStandard   = "STANDARD"
+	StorageClassStandardIA = "STANDARD_IA"
+	StorageClassCold       = "COLD"
+	StorageClassArchive    = "ARCHIVE"
+)
+
+type PutObjectOptions struct {
+	// ContentType is detected from the object name, then by sniffing the body, when empty.
+	ContentType        string
+	ContentDisposition string
+	ContentEncoding    string
+	CacheControl       string
+	Expires            time.Time
+	StorageClass       string
+	CannedAcl          string
+	ContentMD5         string
+	ContentSHA256      string
+	ContentCRC32       string
+
+	// ContentLength is required when body is a plain stream; without it the body is
+	// buffered in memory to find its length.
+	ContentLength int64
+
+	UserMeta map[string]string
+
+	// Encryption, when set, has BOS encrypt the object at rest.
+	Encryption *ServerSideEncryption
+}
+
+// setHeaders adds the object headers described by opts, other than the ones describing
+// the request body itself, to headers.
+func (opts *PutObjectOptions) setHeaders(headers map[string]string) {
+	if opts.ContentDisposition != "" {
+		headers[httplib.CONTENT_DISPOSITION] = opts.ContentDisposition
+	}
+	if opts.ContentEncoding != "" {
+		headers[httplib.CONTENT_ENCODING] = opts.ContentEncoding
+	}
+	if opts.CacheControl != "" {
+		headers[httplib.CACHE_CONTROL] = opts.CacheControl
+	}
+	if !opts.Expires.IsZero() {
+		headers[httplib.EXPIRES] = opts.Expires.UTC().Format(http.TimeFormat)
+	}
+	if opts.StorageClass != "" {
+		headers[auth.BCE_STORAGE_CLASS] = opts.StorageClass
+	}
+	if opts.CannedAcl != "" {
+		headers[auth.BCE_ACL] = opts.CannedAcl
+	}
+	for k, v := range opts.UserMeta {
+		headers[auth.BCE_USER_METADATA_PREFIX+k] = v
+	}
+	opts.Encryption.setHeaders(headers)
+}
+
+func (c *BosClient) PutObjectWithOptions(bucketName, objectName string, body io.Reader,
+	opts *PutObjectOptions) (eTag string, err error) {
+
+	if opts == nil {
+		opts = &PutObjectOptions{}
+	}
+	if body == nil {
+		body = bytes.NewReader(nil)
+	}
+
 	objectName = c.formatPath(objectName)
 	req := &httplib.Request{
 		Method:  httplib.PUT,
@@ -328,22 +552,30 @@ func (c *BosClient) PutObject(bucketName, objectName string, body *bytes.Reader,
 		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
 	}
 
-	if contentMD5 != "" {
-		req.Headers[httplib.CONTENT_MD5] = contentMD5
+	body, size, err := sizedBody(body, opts.ContentLength)
+	if err != nil {
+		return "", err
 	}
 
-	if contentSHA256 != "" {
-		req.Headers[auth.BCE_CONTENT_SHA256] = contentSHA256
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType, body = detectContentType(objectName, body)
 	}
 
-	if metaInfo != nil {
-		for k, v := range metaInfo {
-			bceMeta := fmt.Sprintf("%s%s", auth.BCE_USER_METADATA_PREFIX, k)
-			req.Headers[bceMeta] = v
-		}
+	if opts.ContentMD5 != "" {
+		req.Headers[httplib.CONTENT_MD5] = opts.ContentMD5
+	}
+	if opts.ContentSHA256 != "" {
+		req.Headers[auth.BCE_CONTENT_SHA256] = opts.ContentSHA256
 	}
+	if opts.ContentCRC32 != "" {
+		req.Headers[auth.BCE_CONTENT_CRC32] = opts.ContentCRC32
+	}
+	opts.setHeaders(req.Headers)
 
 	req.Body = body
+	req.ContentLength = size
+	req.Type = contentType
 
 	res, err := c.DoRequest(req)
 	if err == nil {
@@ -352,6 +584,47 @@ func (c *BosClient) PutObject(bucketName, objectName string, body *bytes.Reader,
 	return
 }
 
+// sizedBody returns body with its length, limited to contentLength when that is set.
+// Bodies of unknown length are read into memory to find it.
+func sizedBody(body io.Reader, contentLength int64) (io.Reader, int64, error) {
+	if contentLength > 0 {
+		return io.LimitReader(body, contentLength), contentLength, nil
+	}
+	if n, ok := readerLength(body); ok {
+		return body, n, nil
+	}
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(content), int64(len(content)), nil
+}
+
+// readerLength reports how many bytes are left in r, if that can be known without
+// consuming it.
+func readerLength(r io.Reader) (int64, bool) {
+	switch v := r.(type) {
+	case interface {
+		Len() int
+	}:
+		return int64(v.Len()), true
+	case io.Seeker:
+		cur, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, false
+		}
+		if _, err = v.Seek(cur, io.SeekStart); err != nil {
+			return 0, false
+		}
+		return end - cur, true
+	}
+	return 0, false
+}
+
 /*
  * Name: InitiateMultipartUpload
  * URL: http://bce.baidu.com/doc/BOS/API.html#InitiateMultipartUpload.E6.8E.A5.E5.8F.A3
@@ -364,6 +637,22 @@ type MultipartUploadResponse struct {
 }
 
 func (c *BosClient) InitiateMultipartUpload(bucketName, objectName, contentType string) (output *MultipartUploadResponse, err error) {
+	if contentType == "" {
+		contentType = httplib.OCTET_STREAM
+	}
+	return c.InitiateMultipartUploadWithOptions(bucketName, objectName, &PutObjectOptions{ContentType: contentType})
+}
+
+// InitiateMultipartUploadWithOptions starts a multipart upload whose object will carry the
+// headers described by opts. The content checksums and length in opts are ignored, they
+// belong to the individual parts. An empty ContentType is detected from the object name.
+func (c *BosClient) InitiateMultipartUploadWithOptions(bucketName, objectName string,
+	opts *PutObjectOptions) (output *MultipartUploadResponse, err error) {
+
+	if opts == nil {
+		opts = &PutObjectOptions{}
+	}
+
 	objectName = c.formatPath(objectName)
 	req := &httplib.Request{
 		Method:  httplib.POST,
@@ -372,10 +661,14 @@ func (c *BosClient) InitiateMultipartUpload(bucketName, objectName, contentType
 		Query:   "uploads",
 	}
 
-	req.Headers[httplib.CONTENT_TYPE] = httplib.OCTET_STREAM
-	if contentType != "" {
-		req.Headers[httplib.CONTENT_TYPE] = contentType
+	req.Headers[httplib.CONTENT_TYPE] = opts.ContentType
+	if opts.ContentType == "" {
+		req.Headers[httplib.CONTENT_TYPE] = contentTypeByExtension(objectName)
+		if req.Headers[httplib.CONTENT_TYPE] == "" {
+			req.Headers[httplib.CONTENT_TYPE] = httplib.OCTET_STREAM
+		}
 	}
+	opts.setHeaders(req.Headers)
 
 	res, err := c.DoRequest(req)
 	if err != nil {
@@ -400,15 +693,48 @@ func (c *BosClient) InitiateMultipartUpload(bucketName, objectName, contentType
  */
 
 func (c *BosClient) UploadPart(bucketName, objectName, uploadId, partNumber string, body *bytes.Reader) (eTag string, err error) {
+	n, err := strconv.Atoi(partNumber)
+	if err != nil {
+		return "", fmt.Errorf("invalid part number %q: %v", partNumber, err)
+	}
+	return c.UploadPartWithOptions(bucketName, objectName, uploadId, n, body, nil)
+}
+
+type UploadPartOptions struct {
+	// ContentLength must be set when body has no Len method.
+	ContentLength int64
+	ContentMD5    string
+	ContentCRC32  string
+
+	// Encryption must repeat the customer key the upload was initiated with, if any.
+	Encryption *ServerSideEncryption
+}
+
+func (c *BosClient) UploadPartWithOptions(bucketName, objectName, uploadId string, partNumber int,
+	body io.Reader, opts *UploadPartOptions) (eTag string, err error) {
+
+	if opts == nil {
+		opts = &UploadPartOptions{}
+	}
+
 	objectName = c.formatPath(objectName)
 	req := &httplib.Request{
 		Method:  httplib.PUT,
 		Headers: map[string]string{},
 		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
-		Query:   "uploadId=" + uploadId + "&partNumber=" + partNumber,
+		Query:   fmt.Sprintf("uploadId=%s&partNumber=%d", uploadId, partNumber),
+	}
+
+	if opts.ContentMD5 != "" {
+		req.Headers[httplib.CONTENT_MD5] = opts.ContentMD5
+	}
+	if opts.ContentCRC32 != "" {
+		req.Headers[auth.BCE_CONTENT_CRC32] = opts.ContentCRC32
 	}
+	opts.Encryption.customerKeyOnly().setHeaders(req.Headers)
 
 	req.Body = body
+	req.ContentLength = opts.ContentLength
 
 	res, err := c.DoRequest(req)
 	if err == nil {
@@ -509,17 +835,41 @@ type ListPartsResponse struct {
 	Parts                []PartInfo
 }
 
+// ListParts lists one page of the parts uploaded so far. partNumberMarker and maxParts
+// may be nil to leave them out; see ListPartsWithArgs for a typed form.
 func (c *BosClient) ListParts(bucketName, objectName, uploadId string, partNumberMarker,
 	maxParts interface{}) (output *ListPartsResponse, err error) {
 
+	args := &ListPartsArgs{}
+	if args.PartNumberMarker, err = intArg(partNumberMarker); err != nil {
+		return nil, err
+	}
+	if args.MaxParts, err = intArg(maxParts); err != nil {
+		return nil, err
+	}
+	return c.ListPartsWithArgs(bucketName, objectName, uploadId, args)
+}
+
+type ListPartsArgs struct {
+	PartNumberMarker int
+	MaxParts         int
+}
+
+func (c *BosClient) ListPartsWithArgs(bucketName, objectName, uploadId string,
+	args *ListPartsArgs) (output *ListPartsResponse, err error) {
+
+	if args == nil {
+		args = &ListPartsArgs{}
+	}
+
 	objectName = c.formatPath(objectName)
 	query := []string{}
 	query = append(query, "uploadId="+uploadId)
-	if partNumberMarker != nil {
-		query = append(query, "partNumberMarker="+partNumberMarker.(string))
+	if args.PartNumberMarker > 0 {
+		query = append(query, "partNumberMarker="+strconv.Itoa(args.PartNumberMarker))
 	}
-	if maxParts != nil {
-		query = append(query, "maxParts="+maxParts.(string))
+	if args.MaxParts > 0 {
+		query = append(query, "maxParts="+strconv.Itoa(args.MaxParts))
 	}
 	req := &httplib.Request{
 		Method:  httplib.GET,
@@ -558,36 +908,64 @@ type UploadInfo struct {
 	Initiated  string
 }
 type ListMultipartUploadsResponse struct {
-	BucketName     string `json:"bucket"`
-	CommonPrefixes string `json:"commonPrefixes"`
-	Prefix         string `json:"prefix"`
-	KeyMarker      string `json:"keyMarker"`
-	NextKeyMarker  string `json:"nextMarker"`
-	MaxUploads     int64  `json:"maxUploads"`
-	IsTruncated    bool   `json:"isTruncated"`
+	BucketName     string       `json:"bucket"`
+	CommonPrefixes []PrefixInfo `json:"commonPrefixes"`
+	Prefix         string       `json:"prefix"`
+	KeyMarker      string       `json:"keyMarker"`
+	NextKeyMarker  string       `json:"nextKeyMarker"`
+	MaxUploads     int64        `json:"maxUploads"`
+	IsTruncated    bool         `json:"isTruncated"`
 	Uploads        []UploadInfo
 }
 
+// ListMultipartUploads lists one page of the multipart uploads in progress. Each of
+// delimiter, keyMarker, maxUploads and prefix may be nil to leave it out; see
+// ListMultipartUploadsWithArgs for a typed form.
 func (c *BosClient) ListMultipartUploads(bucketName string,
 	delimiter, keyMarker, maxUploads, prefix interface{}) (output ListMultipartUploadsResponse, err error) {
 
+	args := &ListMultipartUploadsArgs{
+		Delimiter: stringArg(delimiter),
+		KeyMarker: stringArg(keyMarker),
+		Prefix:    stringArg(prefix),
+	}
+	if args.MaxUploads, err = intArg(maxUploads); err != nil {
+		return
+	}
+	res, err := c.ListMultipartUploadsWithArgs(bucketName, args)
+	if err != nil {
+		return
+	}
+	return *res, nil
+}
+
+type ListMultipartUploadsArgs struct {
+	Delimiter  string
+	KeyMarker  string
+	MaxUploads int
+	Prefix     string
+}
+
+func (c *BosClient) ListMultipartUploadsWithArgs(bucketName string,
+	args *ListMultipartUploadsArgs) (output *ListMultipartUploadsResponse, err error) {
+
+	if args == nil {
+		args = &ListMultipartUploadsArgs{}
+	}
+
 	query := []string{}
 	query = append(query, "uploads=")
-	if delimiter != nil {
-		query = append(query, "delimiter="+delimiter.(string))
-	}
-	if delimiter != nil {
-		query = append(query, "delimiter="+delimiter.(string))
+	if args.Delimiter != "" {
+		query = append(query, "delimiter="+queryEscape(args.Delimiter))
 	}
-	if keyMarker != nil {
-		query = append(query, "keyMarker="+keyMarker.(string))
+	if args.KeyMarker != "" {
+		query = append(query, "keyMarker="+queryEscape(args.KeyMarker))
 	}
-	if maxUploads != nil {
-		query = append(query, "maxUploads="+maxUploads.(string))
+	if args.MaxUploads > 0 {
+		query = append(query, "maxUploads="+strconv.Itoa(args.MaxUploads))
 	}
-	if prefix != nil {
-		prefix = c.formatPath(prefix.(string))
-		query = append(query, "prefix="+prefix.(string))
+	if args.Prefix != "" {
+		query = append(query, "prefix="+queryEscape(c.formatPath(args.Prefix)))
 	}
 	req := &httplib.Request{
 		Method:  httplib.GET,
@@ -598,18 +976,20 @@ func (c *BosClient) ListMultipartUploads(bucketName string,
 
 	res, err := c.DoRequest(req)
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return
+		return nil, err
 	}
 
+	var response ListMultipartUploadsResponse
+
 	j := json.NewDecoder(strings.NewReader(string(body)))
-	j.Decode(&output)
+	j.Decode(&response)
 
-	return
+	return &response, nil
 }
 
 /*
@@ -623,36 +1003,179 @@ type CopyObjectResponse struct {
 }
 
 func (c *BosClient) CopyObject(srcBucketName, srcObjectName, destBucketName, destObjectName, eTag, metaDirect string) (output CopyObjectResponse, err error) {
+	opts := &CopyObjectOptions{}
+	opts.IfMatch = eTag
+	if metaDirect == MetadataDirectiveCopy || metaDirect == MetadataDirectiveReplace {
+		opts.MetadataDirective = metaDirect
+	}
+	res, err := c.CopyObjectWithOptions(srcBucketName, srcObjectName, destBucketName, destObjectName, opts)
+	if err != nil {
+		return
+	}
+	return *res, nil
+}
+
+const (
+	MetadataDirectiveCopy    = "copy"
+	MetadataDirectiveReplace = "replace"
+)
+
+// MaxSingleCopySize is the largest object CopyObject can copy in one request. Larger
+// objects must be copied part by part with UploadPartCopy.
+const MaxSingleCopySize = 5 * 1024 * 1024 * 1024
+
+// CopySourceConditions makes a copy depend on the state of its source. A copy whose
+// conditions do not hold fails with a *PreconditionFailedError. ETags may be given with
+// or without their quotes.
+type CopySourceConditions struct {
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   time.Time
+	IfUnmodifiedSince time.Time
+}
+
+func (cond *CopySourceConditions) setHeaders(headers map[string]string) {
+	if cond.IfMatch != "" {
+		headers[auth.BCE_COPY_SOURCE_IF_MATCH] = quoteETag(cond.IfMatch)
+	}
+	if cond.IfNoneMatch != "" {
+		headers[auth.BCE_COPY_SOURCE_IF_NONE_MATCH] = quoteETag(cond.IfNoneMatch)
+	}
+	if !cond.IfModifiedSince.IsZero() {
+		headers[auth.BCE_COPY_SOURCE_IF_MODIFIED_SINCE] = cond.IfModifiedSince.UTC().Format(http.TimeFormat)
+	}
+	if !cond.IfUnmodifiedSince.IsZero() {
+		headers[auth.BCE_COPY_SOURCE_IF_UNMODIFIED_SINCE] = cond.IfUnmodifiedSince.UTC().Format(http.TimeFormat)
+	}
+}
+
+type CopyObjectOptions struct {
+	CopySourceConditions
+
+	// MetadataDirective is MetadataDirectiveCopy, the default, to keep the source's
+	// headers and user metadata, or MetadataDirectiveReplace to use Meta instead.
+	MetadataDirective string
+
+	// Meta describes the copy. All of it applies with MetadataDirectiveReplace; with
+	// MetadataDirectiveCopy only StorageClass, CannedAcl and Encryption are used.
+	Meta *PutObjectOptions
+
+	// SourceEncryption holds the customer key of a source encrypted with one.
+	SourceEncryption *ServerSideEncryption
+}
+
+func (opts *CopyObjectOptions) setHeaders(headers map[string]string) {
+	opts.CopySourceConditions.setHeaders(headers)
+	opts.SourceEncryption.setCopySourceHeaders(headers)
+	if opts.MetadataDirective != "" {
+		headers[auth.BCE_COPY_METADATA_DIRECTIVE] = opts.MetadataDirective
+	}
+	if opts.Meta == nil {
+		return
+	}
+	if opts.MetadataDirective == MetadataDirectiveReplace {
+		if opts.Meta.ContentType != "" {
+			headers[httplib.CONTENT_TYPE] = opts.Meta.ContentType
+		}
+		opts.Meta.setHeaders(headers)
+		return
+	}
+	if opts.Meta.StorageClass != "" {
+		headers[auth.BCE_STORAGE_CLASS] = opts.Meta.StorageClass
+	}
+	if opts.Meta.CannedAcl != "" {
+		headers[auth.BCE_ACL] = opts.Meta.CannedAcl
+	}
+	opts.Meta.Encryption.setHeaders(headers)
+}
+
+// CopyObjectWithOptions copies an object of up to MaxSingleCopySize bytes within BOS in
+// a single request. See Uploader.CopyLargeObject for larger objects.
+func (c *BosClient) CopyObjectWithOptions(srcBucketName, srcObjectName, destBucketName, destObjectName string,
+	opts *CopyObjectOptions) (output *CopyObjectResponse, err error) {
+
+	if opts == nil {
+		opts = &CopyObjectOptions{}
+	}
+
 	destObjectName = c.formatPath(destObjectName)
 	req := &httplib.Request{
 		Method:  httplib.PUT,
 		Headers: map[string]string{},
 		Path:    c.APIVersion + "/" + destBucketName + "/" + destObjectName,
 	}
-	req.Headers[auth.BCE_COPY_SOURCE] = utils.UriEncodeExceptSlash("/" + srcBucketName + "/" + srcObjectName)
+	req.Headers[auth.BCE_COPY_SOURCE] = c.copySource(srcBucketName, srcObjectName)
+	opts.setHeaders(req.Headers)
 
-	if eTag != "" {
-		req.Headers[auth.BCE_COPY_SOURCE_IF_MATCH] = eTag
-	}
+	return c.doCopy(req)
+}
 
-	if metaDirect == "copy" || metaDirect == "replace" {
-		req.Headers[auth.BCE_COPY_METADATA_DIRECTIVE] = metaDirect
-	}
+func (c *BosClient) copySource(bucketName, objectName string) string {
+	return utils.UriEncodeExceptSlash("/" + bucketName + "/" + c.formatPath(objectName))
+}
 
+func (c *BosClient) doCopy(req *httplib.Request) (*CopyObjectResponse, error) {
 	res, err := c.DoRequest(req)
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return
+		return nil, err
 	}
 
+	var response CopyObjectResponse
 	j := json.NewDecoder(strings.NewReader(string(body)))
-	j.Decode(&output)
+	j.Decode(&response)
+	return &response, nil
+}
 
-	return
+/*
+ * Name: UploadPartCopy
+ * URL: http://bce.baidu.com/doc/BOS/API.html#UploadPartCopy.E6.8E.A5.E5.8F.A3
+ */
+
+type UploadPartCopyOptions struct {
+	CopySourceConditions
+
+	// SourceEncryption holds the customer key of a source encrypted with one.
+	SourceEncryption *ServerSideEncryption
+
+	// Encryption must repeat the customer key the upload was initiated with, if any.
+	Encryption *ServerSideEncryption
+}
+
+// UploadPartCopy sets part partNumber of a multipart upload to bytes of an existing
+// object: all of it when rng is nil, otherwise the given range, which must not be
+// open-ended. The returned ETag is the one to pass to CompleteMultipartUpload.
+func (c *BosClient) UploadPartCopy(srcBucketName, srcObjectName, destBucketName, destObjectName, uploadId string,
+	partNumber int, rng *ObjectRange, opts *UploadPartCopyOptions) (output *CopyObjectResponse, err error) {
+
+	destObjectName = c.formatPath(destObjectName)
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{},
+		Path:    c.APIVersion + "/" + destBucketName + "/" + destObjectName,
+		Query:   fmt.Sprintf("uploadId=%s&partNumber=%d", uploadId, partNumber),
+	}
+	req.Headers[auth.BCE_COPY_SOURCE] = c.copySource(srcBucketName, srcObjectName)
+	if rng != nil {
+		if err = rng.validate(); err != nil {
+			return nil, err
+		}
+		if rng.End < 0 {
+			return nil, fmt.Errorf("copy source range %d- must have an end", rng.Start)
+		}
+		req.Headers[auth.BCE_COPY_SOURCE_RANGE] = rng.String()
+	}
+	if opts != nil {
+		opts.CopySourceConditions.setHeaders(req.Headers)
+		opts.SourceEncryption.setCopySourceHeaders(req.Headers)
+		opts.Encryption.customerKeyOnly().setHeaders(req.Headers)
+	}
+
+	return c.doCopy(req)
 }
 
 /*
@@ -661,40 +1184,92 @@ func (c *BosClient) CopyObject(srcBucketName, srcObjectName, destBucketName, des
  */
 type GetObjectResponse struct {
 	Body io.ReadCloser
-	ETag string
-	Size int
-	Meta map[string]string
+
+	// ContentLength is the number of bytes in Body, less than Size for a ranged read.
+	ContentLength int64
+	ObjectMeta
 }
 
+// GetObject fetches an object. For historical reasons a range is only sent when
+// endPos > 0, and an endPos not after startPos asks for everything from startPos; use
+// GetObjectWithOptions for exact ranges.
 func (c *BosClient) GetObject(bucketName, objectName string, startPos, endPos int64) (output GetObjectResponse, err error) {
+	opts := &GetObjectOptions{}
+	if startPos >= 0 && endPos > 0 {
+		if endPos > startPos {
+			opts.Range = &ObjectRange{Start: startPos, End: endPos}
+		} else {
+			opts.Range = &ObjectRange{Start: startPos, End: -1}
+		}
+	}
+	return c.GetObjectWithOptions(bucketName, objectName, opts)
+}
+
+// ObjectRange selects bytes Start through End, inclusive, of an object. A negative End
+// reads to the end of the object.
+type ObjectRange struct {
+	Start int64
+	End   int64
+}
+
+func (r *ObjectRange) String() string {
+	if r.End < 0 {
+		return fmt.Sprintf("bytes=%d-", r.Start)
+	}
+	return fmt.Sprintf("bytes=%d-%d", r.Start, r.End)
+}
+
+func (r *ObjectRange) validate() error {
+	if r.Start < 0 || (r.End >= 0 && r.End < r.Start) {
+		return fmt.Errorf("invalid object range %d-%d", r.Start, r.End)
+	}
+	return nil
+}
+
+type GetObjectOptions struct {
+	ObjectConditions
+
+	// Range, when set, limits the response to part of the object.
+	Range *ObjectRange
+
+	// Encryption holds the customer key of an object encrypted with one.
+	Encryption *ServerSideEncryption
+}
+
+func (c *BosClient) GetObjectWithOptions(bucketName, objectName string,
+	opts *GetObjectOptions) (output GetObjectResponse, err error) {
+
+	if opts == nil {
+		opts = &GetObjectOptions{}
+	}
+
 	objectName = c.formatPath(objectName)
 	req := &httplib.Request{
 		Method:  httplib.GET,
 		Headers: map[string]string{},
 		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
 	}
-	if startPos >= 0 && endPos > 0 {
-		if endPos > startPos {
-			req.Headers[httplib.RANGE] = fmt.Sprintf("bytes=%d-%d", startPos, endPos)
-		} else {
-			req.Headers[httplib.RANGE] = fmt.Sprintf("bytes=%d-", startPos)
+	if opts.Range != nil {
+		if err = opts.Range.validate(); err != nil {
+			return
 		}
+		req.Headers[httplib.RANGE] = opts.Range.String()
 	}
+	opts.ObjectConditions.setHeaders(req.Headers)
+	opts.Encryption.customerKeyOnly().setHeaders(req.Headers)
 
 	res, err := c.DoRequest(req)
 	if err != nil {
 		return
 	}
-	output = GetObjectResponse{Body: res.Body, Meta: map[string]string{}}
-	eTag := strings.Replace(res.Header.Get("ETag"), "\"", "", -1)
-	output.ETag = eTag
-	size, _ := strconv.Atoi(res.Header.Get("Content-Length"))
-	output.Size = size
-
-	for k, v := range res.Header {
-		if strings.HasPrefix(strings.ToLower(k), auth.BCE_USER_METADATA_PREFIX) {
-			output.Meta[k] = v[0]
-		}
+	meta, err := parseObjectMeta(res.Header)
+	if err != nil {
+		res.Body.Close()
+		return
+	}
+	output = GetObjectResponse{Body: res.Body, ContentLength: meta.Size, ObjectMeta: *meta}
+	if total, ok := contentRangeSize(res.Header.Get("Content-Range")); ok {
+		output.Size = total
 	}
 	return
 }
@@ -704,28 +1279,147 @@ func (c *BosClient) GetObject(bucketName, objectName string, startPos, endPos in
  * URL: http://bce.baidu.com/doc/BOS/API.html#GetObjectMeta.E6.8E.A5.E5.8F.A3
  */
 
-func (c *BosClient) GetObjectMeta(bucketName, objectName string) (output map[string]string, err error) {
+// ObjectMeta is what BOS reports about an object along with its content.
+type ObjectMeta struct {
+	// Size is -1 when the response did not give it.
+	Size         int64     `json:"size"`
+	ETag         string    `json:"eTag"`
+	LastModified time.Time `json:"lastModified"`
+	StorageClass string    `json:"storageClass"`
+
+	ContentType        string    `json:"-"`
+	ContentEncoding    string    `json:"-"`
+	ContentDisposition string    `json:"-"`
+	CacheControl       string    `json:"-"`
+	Expires            time.Time `json:"-"`
+	ContentMD5         string    `json:"-"`
+	ContentCRC32       string    `json:"-"`
+
+	// UserMeta is keyed by lower-case name, without the x-bce-meta- prefix.
+	UserMeta map[string]string `json:"-"`
+
+	ServerSideEncryption string `json:"-"`
+	KmsKeyId             string `json:"-"`
+	CustomerKeyMD5       string `json:"-"`
+
+	// ObjectType is ObjectTypeAppendable for objects written with AppendObject, which
+	// take their next append at NextAppendOffset.
+	ObjectType       string `json:"-"`
+	NextAppendOffset int64  `json:"-"`
+
+	// Restore is set for an ARCHIVE object once RestoreObject has been called on it.
+	Restore *RestoreStatus `json:"-"`
+
+	// ReplicationStatus is one of the ObjectReplication* values for an object covered
+	// by a bucket replication rule, and empty otherwise.
+	ReplicationStatus string `json:"-"`
+}
+
+// parseObjectMeta reads the object metadata from the headers of a response about it.
+func parseObjectMeta(header http.Header) (*ObjectMeta, error) {
+	meta := &ObjectMeta{
+		Size:               -1,
+		ETag:               strings.Trim(header.Get("ETag"), "\""),
+		StorageClass:       header.Get(auth.BCE_STORAGE_CLASS),
+		ContentType:        header.Get(httplib.CONTENT_TYPE),
+		ContentEncoding:    header.Get(httplib.CONTENT_ENCODING),
+		ContentDisposition: header.Get(httplib.CONTENT_DISPOSITION),
+		CacheControl:       header.Get(httplib.CACHE_CONTROL),
+		ContentMD5:         header.Get(httplib.CONTENT_MD5),
+		ContentCRC32:       header.Get(auth.BCE_CONTENT_CRC32),
+		UserMeta:           map[string]string{},
+	}
+	if s := header.Get(httplib.CONTENT_LENGTH); s != "" {
+		size, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("bad Content-Length %q", s)
+		}
+		meta.Size = size
+	}
+	if t, err := time.Parse(http.TimeFormat, header.Get("Last-Modified")); err == nil {
+		meta.LastModified = t
+	}
+	if t, err := time.Parse(http.TimeFormat, header.Get(httplib.EXPIRES)); err == nil {
+		meta.Expires = t
+	}
+	meta.ServerSideEncryption, meta.KmsKeyId, meta.CustomerKeyMD5 = encryptionState(header)
+	meta.ObjectType = header.Get(auth.BCE_OBJECT_TYPE)
+	meta.Restore = parseRestoreStatus(header.Get(auth.BCE_RESTORE))
+	meta.ReplicationStatus = header.Get(auth.BCE_REPLICATION_STATUS)
+	if s := header.Get(auth.BCE_NEXT_APPEND_OFFSET); s != "" {
+		offset, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s %q", auth.BCE_NEXT_APPEND_OFFSET, s)
+		}
+		meta.NextAppendOffset = offset
+	}
+	for k, v := range header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, auth.BCE_USER_METADATA_PREFIX) && len(v) > 0 {
+			meta.UserMeta[k[len(auth.BCE_USER_METADATA_PREFIX):]] = v[0]
+		}
+	}
+	return meta, nil
+}
+
+// contentRangeSize returns the full object size from a "bytes start-end/size"
+// Content-Range header.
+func contentRangeSize(contentRange string) (int64, bool) {
+	i := strings.LastIndex(contentRange, "/")
+	if !strings.HasPrefix(contentRange, "bytes ") || i < 0 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	return size, err == nil
+}
+
+func (c *BosClient) GetObjectMeta(bucketName, objectName string) (output *ObjectMeta, err error) {
+	return c.GetObjectMetaWithOptions(bucketName, objectName, nil)
+}
+
+type GetObjectMetaOptions struct {
+	ObjectConditions
+
+	// Encryption holds the customer key of an object encrypted with one.
+	Encryption *ServerSideEncryption
+}
+
+func (c *BosClient) GetObjectMetaWithOptions(bucketName, objectName string,
+	opts *GetObjectMetaOptions) (output *ObjectMeta, err error) {
+
+	if opts == nil {
+		opts = &GetObjectMetaOptions{}
+	}
+	headers := map[string]string{}
+	opts.ObjectConditions.setHeaders(headers)
+	opts.Encryption.customerKeyOnly().setHeaders(headers)
+
+	header, err := c.headObject(bucketName, objectName, headers)
+	if err != nil {
+		return nil, err
+	}
+	return parseObjectMeta(header)
+}
+
+// headObject sends a HEAD request for the object with the given extra headers and
+// returns the response headers.
+func (c *BosClient) headObject(bucketName, objectName string, headers map[string]string) (http.Header, error) {
 	objectName = c.formatPath(objectName)
 	req := &httplib.Request{
 		Method:  httplib.HEAD,
 		Headers: map[string]string{},
 		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
 	}
+	for k, v := range headers {
+		req.Headers[k] = v
+	}
 
 	res, err := c.DoRequest(req)
 	if err != nil {
-		return
-	}
-	output = map[string]string{}
-	output["Size"] = res.Header.Get("Content-Length")
-	output["eTag"] = res.Header.Get("ETag")
-	for k, v := range res.Header {
-		if strings.HasPrefix(strings.ToLower(k), auth.BCE_USER_METADATA_PREFIX) {
-			output[k] = v[0]
-		}
+		return nil, err
 	}
-
-	return
+	res.Body.Close()
+	return res.Header, nil
 }
 
 /*
@@ -750,9 +1444,223 @@ func (c *BosClient) DeleteObject(bucketName, objectName string) (err error) {
 	return
 }
 
+/*
+ * Name: GetObjectAcl
+ * URL: http://bce.baidu.com/doc/BOS/API.html#GetObjectAcl.E6.8E.A5.E5.8F.A3
+ */
+
+type ObjectAclResponse struct {
+	AccessControlList []GranteeGroup `json:"accessControlList"`
+}
+
+// GetObjectAcl returns the ACL set on an object. Objects without one follow the ACL of
+// their bucket, and BOS answers with an error.
+func (c *BosClient) GetObjectAcl(bucketName, objectName string) (output *ObjectAclResponse, err error) {
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method:  httplib.GET,
+		Headers: map[string]string{},
+		Query:   "acl",
+		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ObjectAclResponse
+
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	return &response, nil
+}
+
+/*
+ * Name: PutObjectAcl
+ * URL: http://bce.baidu.com/doc/BOS/API.html#PutObjectAcl.E6.8E.A5.E5.8F.A3
+ */
+
+// SetObjectAcl gives an object one of the canned ACLs, overriding the bucket's.
+func (c *BosClient) SetObjectAcl(bucketName, objectName, cannedAcl string) (err error) {
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{auth.BCE_ACL: cannedAcl},
+		Query:   "acl",
+		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+// SetObjectAclWithGrants gives an object an ACL made of the given grants, overriding
+// the bucket's.
+func (c *BosClient) SetObjectAclWithGrants(bucketName, objectName string, grants []GranteeGroup) (err error) {
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{},
+		Query:   "acl",
+		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
+		Type:    httplib.JSON,
+	}
+	if req.Body, err = aclBody(grants); err != nil {
+		return
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+/*
+ * Name: DeleteObjectAcl
+ * URL: http://bce.baidu.com/doc/BOS/API.html#DeleteObjectAcl.E6.8E.A5.E5.8F.A3
+ */
+
+// DeleteObjectAcl removes the ACL of an object, which then follows its bucket's again.
+func (c *BosClient) DeleteObjectAcl(bucketName, objectName string) (err error) {
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method:  httplib.DELETE,
+		Headers: map[string]string{},
+		Query:   "acl",
+		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+/*
+ * Name: DeleteMultipleObjects
+ * URL: http://bce.baidu.com/doc/BOS/API.html#DeleteMultipleObjects.E6.8E.A5.E5.8F.A3
+ */
+
+// MaxDeleteObjects is the largest number of keys BOS accepts in one batch delete.
+const MaxDeleteObjects = 1000
+
+// DeleteObjectError reports why one key of a batch delete was not deleted.
+type DeleteObjectError struct {
+	ObjectName string `json:"key"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *DeleteObjectError) Error() string {
+	return fmt.Sprintf("delete %s: %s: %s", e.ObjectName, e.Code, e.Message)
+}
+
+type DeleteMultipleObjectsResponse struct {
+	// Errors lists the keys that were not deleted. Keys that did not exist count as
+	// deleted.
+	Errors []DeleteObjectError `json:"errors"`
+}
+
+// DeleteMultipleObjects deletes the named objects, sending them MaxDeleteObjects at a
+// time. A failure to delete some of the keys is reported in the response, not as err.
+func (c *BosClient) DeleteMultipleObjects(bucketName string, objectNames []string) (output *DeleteMultipleObjectsResponse, err error) {
+	output = &DeleteMultipleObjectsResponse{}
+	for len(objectNames) > 0 {
+		n := len(objectNames)
+		if n > MaxDeleteObjects {
+			n = MaxDeleteObjects
+		}
+		res, err := c.deleteObjectBatch(bucketName, objectNames[:n])
+		if err != nil {
+			return nil, err
+		}
+		output.Errors = append(output.Errors, res.Errors...)
+		objectNames = objectNames[n:]
+	}
+	return output, nil
+}
+
+func (c *BosClient) deleteObjectBatch(bucketName string, objectNames []string) (*DeleteMultipleObjectsResponse, error) {
+	type objectKey struct {
+		Key string `json:"key"`
+	}
+	keys := make([]objectKey, len(objectNames))
+	for i, name := range objectNames {
+		keys[i].Key = c.formatPath(name)
+	}
+	jstring, err := json.Marshal(map[string][]objectKey{"objects": keys})
+	if err != nil {
+		return nil, err
+	}
+
+	req := &httplib.Request{
+		Method:  httplib.POST,
+		Headers: map[string]string{},
+		Path:    c.APIVersion + "/" + bucketName,
+		Query:   "delete",
+		Body:    bytes.NewReader(jstring),
+		Type:    httplib.TEXT,
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// BOS answers with an empty body when every key was deleted.
+	var response DeleteMultipleObjectsResponse
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	return &response, nil
+}
+
 func (c *BosClient) formatPath(objectName string) string {
-	if objectName[0] == '/' {
+	if objectName != "" && objectName[0] == '/' {
 		return objectName[1:]
 	}
 	return objectName
 }
+
+// queryEscape escapes a query parameter value the way BOS canonicalizes it, with spaces
+// as %20 rather than +.
+func queryEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+// stringArg converts an optional untyped list parameter to a string, "" meaning unset.
+func stringArg(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// intArg converts an optional untyped list parameter, given as a number or a decimal
+// string, to an int, 0 meaning unset.
+func intArg(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case string:
+		if n == "" {
+			return 0, nil
+		}
+		i, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q: %v", n, err)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("invalid number %v of type %T", v, v)
+}
@@ -7,8 +7,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spiderorg/bd-video-sdk/auth"
 )
@@ -252,7 +256,7 @@ func TestGetObjectMeta(t *testing.T) {
 		t.Errorf("GetObjectMeta failed.")
 		t.Errorf(err.Error())
 	}
-	if res["eTag"] == "" {
+	if res == nil || res.ETag == "" {
 		t.Errorf("GetObjectMeta failed. eTag Not Match")
 	}
 }
@@ -488,6 +492,210 @@ func TestGetObject(t *testing.T) {
 	c.DeleteObject(TestBukketName, TestObjectName)
 }
 
+func TestPutObjectWithOptions(t *testing.T) {
+	f, c := newFakeBos(t)
+
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	content := []byte("fake mp4 payload")
+	_, err := c.PutObjectWithOptions(TestBukketName, "/videos/clip.mp4", ioutil.NopCloser(bytes.NewReader(content)),
+		&PutObjectOptions{
+			ContentDisposition: "attachment; filename=clip.mp4",
+			ContentEncoding:    "identity",
+			CacheControl:       "max-age=3600",
+			Expires:            expires,
+			StorageClass:       StorageClassStandardIA,
+			CannedAcl:          "public-read",
+			ContentCRC32:       "12345",
+			ContentLength:      int64(len(content)),
+			UserMeta:           map[string]string{"title": "clip"},
+		})
+	if err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+
+	obj := f.object(TestBukketName, "videos/clip.mp4")
+	if obj == nil || string(obj.data) != string(content) {
+		t.Fatalf("PutObjectWithOptions failed. Content Not Match.")
+	}
+	want := map[string]string{
+		"Content-Type":        "video/mp4",
+		"Content-Disposition": "attachment; filename=clip.mp4",
+		"Content-Encoding":    "identity",
+		"Cache-Control":       "max-age=3600",
+		"Expires":             "Wed, 02 Jan 2030 03:04:05 GMT",
+		"X-Bce-Storage-Class": StorageClassStandardIA,
+		"X-Bce-Acl":           "public-read",
+		"X-Bce-Content-Crc32": "12345",
+		"X-Bce-Meta-Title":    "clip",
+	}
+	for k, v := range want {
+		if got := obj.header.Get(k); got != v {
+			t.Errorf("PutObjectWithOptions failed. %s = %q, want %q", k, got, v)
+		}
+	}
+	if req := f.lastRequest("PUT"); req.ContentLength != int64(len(content)) {
+		t.Errorf("PutObjectWithOptions failed. Content-Length = %d", req.ContentLength)
+	}
+
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	_, err = c.PutObjectWithOptions(TestBukketName, "thumbnail", ioutil.NopCloser(bytes.NewReader(png)), nil)
+	if err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+	obj = f.object(TestBukketName, "thumbnail")
+	if got := obj.header.Get("Content-Type"); got != "image/png" {
+		t.Errorf("PutObjectWithOptions failed. sniffed Content-Type = %q", got)
+	}
+	if string(obj.data) != string(png) {
+		t.Errorf("PutObjectWithOptions failed. Content Not Match after sniffing.")
+	}
+}
+
+func TestDeleteMultipleObjects(t *testing.T) {
+	f, c := newFakeBos(t)
+	var keys []string
+	for i := 0; i < MaxDeleteObjects+5; i++ {
+		key := fmt.Sprintf("hls/seg-%04d.ts", i)
+		f.putObject(TestBukketName, key, []byte("ts"), nil)
+		keys = append(keys, "/"+key)
+	}
+	f.putObject(TestBukketName, "keep.mp4", []byte("mp4"), nil)
+
+	res, err := c.DeleteMultipleObjects(TestBukketName, keys)
+	if err != nil || len(res.Errors) != 0 {
+		t.Fatalf("DeleteMultipleObjects failed. %v %+v", err, res)
+	}
+	f.mu.Lock()
+	left := len(f.objects[TestBukketName])
+	f.mu.Unlock()
+	if left != 1 || f.object(TestBukketName, "keep.mp4") == nil {
+		t.Errorf("DeleteMultipleObjects failed. %d objects left", left)
+	}
+
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		f.reply(w, map[string]interface{}{"errors": []map[string]string{
+			{"key": "locked.mp4", "code": "AccessDenied", "message": "Access denied."},
+		}})
+		return true
+	}
+	res, err = c.DeleteMultipleObjects(TestBukketName, []string{"a.mp4", "locked.mp4"})
+	if err != nil || len(res.Errors) != 1 || res.Errors[0].ObjectName != "locked.mp4" ||
+		res.Errors[0].Code != "AccessDenied" {
+		t.Errorf("DeleteMultipleObjects failed. %v %+v", err, res)
+	}
+}
+
+func TestObjectAcl(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "previews/clip.mp4", []byte("clip"), nil)
+
+	if _, err := c.GetObjectAcl(TestBukketName, "previews/clip.mp4"); err == nil {
+		t.Errorf("GetObjectAcl should fail before an ACL is set")
+	}
+
+	if err := c.SetObjectAcl(TestBukketName, "/previews/clip.mp4", CannedAclPublicRead); err != nil {
+		t.Fatalf("SetObjectAcl failed. %v", err)
+	}
+	acl, err := c.GetObjectAcl(TestBukketName, "previews/clip.mp4")
+	if err != nil || len(acl.AccessControlList) != 2 ||
+		acl.AccessControlList[1].Grantee[0].Id != GranteeAllUsers {
+		t.Fatalf("GetObjectAcl failed. %v %+v", err, acl)
+	}
+
+	grants := []GranteeGroup{{
+		Grantee:    []GranteeInfo{{Id: GranteeAllUsers}},
+		Permission: []string{PermissionRead},
+		Condition: &AclCondition{
+			Referer:   &RefererCondition{StringLike: []string{"https://*.example.com/*"}},
+			IpAddress: []string{"192.168.0.0/16"},
+		},
+	}}
+	if err = c.SetObjectAclWithGrants(TestBukketName, "previews/clip.mp4", grants); err != nil {
+		t.Fatalf("SetObjectAclWithGrants failed. %v", err)
+	}
+	if req := f.lastRequest("PUT"); !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		t.Errorf("SetObjectAclWithGrants sent Content-Type %q", req.Header.Get("Content-Type"))
+	}
+	acl, err = c.GetObjectAcl(TestBukketName, "previews/clip.mp4")
+	if err != nil || !reflect.DeepEqual(acl.AccessControlList, grants) {
+		t.Errorf("GetObjectAcl did not return the grants set. %v %+v", err, acl)
+	}
+
+	if err = c.DeleteObjectAcl(TestBukketName, "previews/clip.mp4"); err != nil {
+		t.Fatalf("DeleteObjectAcl failed. %v", err)
+	}
+	if _, err = c.GetObjectAcl(TestBukketName, "previews/clip.mp4"); err == nil {
+		t.Errorf("GetObjectAcl should fail after DeleteObjectAcl")
+	}
+	if err = c.SetObjectAclWithGrants(TestBukketName, "previews/clip.mp4", nil); err == nil {
+		t.Errorf("SetObjectAclWithGrants should reject an empty grant list")
+	}
+}
+
+func TestSetBucketAclWithGrants(t *testing.T) {
+	_, c := newFakeBos(t)
+	grants := []GranteeGroup{
+		{Grantee: []GranteeInfo{{Id: "owner-id"}}, Permission: []string{PermissionFullControl}},
+		{Grantee: []GranteeInfo{{Id: "cdn-id"}, {Id: "transcoder-id"}}, Permission: []string{PermissionRead, PermissionList}},
+	}
+	if err := c.SetBucketAclWithGrants(TestBukketName, grants); err != nil {
+		t.Fatalf("SetBucketAclWithGrants failed. %v", err)
+	}
+	acl, err := c.GetBucketAcl(TestBukketName)
+	if err != nil || !reflect.DeepEqual(acl.AccessControlList, grants) {
+		t.Errorf("GetBucketAcl did not return the grants set. %v %+v", err, acl)
+	}
+}
+
+func TestObjectMetaFields(t *testing.T) {
+	_, c := newFakeBos(t)
+
+	content := []byte("0123456789")
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	_, err := c.PutObjectWithOptions(TestBukketName, "meta.mp4", bytes.NewReader(content), &PutObjectOptions{
+		ContentDisposition: "attachment",
+		ContentEncoding:    "identity",
+		CacheControl:       "no-cache",
+		Expires:            expires,
+		StorageClass:       StorageClassStandardIA,
+		ContentCRC32:       "12345",
+		UserMeta:           map[string]string{"Title": "meta"},
+	})
+	if err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+	sum := md5.Sum(content)
+
+	meta, err := c.GetObjectMeta(TestBukketName, "meta.mp4")
+	if err != nil {
+		t.Fatalf("GetObjectMeta failed. %v", err)
+	}
+	if meta.Size != int64(len(content)) || meta.ETag != fmt.Sprintf("%x", sum) || meta.LastModified.IsZero() ||
+		meta.ContentType != "video/mp4" || meta.ContentDisposition != "attachment" ||
+		meta.ContentEncoding != "identity" || meta.CacheControl != "no-cache" || !meta.Expires.Equal(expires) ||
+		meta.StorageClass != StorageClassStandardIA || meta.ContentCRC32 != "12345" ||
+		!reflect.DeepEqual(meta.UserMeta, map[string]string{"title": "meta"}) {
+		t.Errorf("GetObjectMeta returned %+v", meta)
+	}
+
+	res, err := c.GetObjectWithOptions(TestBukketName, "meta.mp4", &GetObjectOptions{Range: &ObjectRange{Start: 2, End: 4}})
+	if err != nil {
+		t.Fatalf("GetObjectWithOptions failed. %v", err)
+	}
+	res.Body.Close()
+	if res.ContentLength != 3 || res.Size != int64(len(content)) || res.ETag != meta.ETag || res.UserMeta["title"] != "meta" {
+		t.Errorf("GetObjectWithOptions returned %+v", res.ObjectMeta)
+	}
+
+	list, err := c.ListObjects(TestBukketName, nil, nil, nil, "meta")
+	if err != nil || len(list.Contents) != 1 {
+		t.Fatalf("ListObjects failed. %v", err)
+	}
+	if obj := list.Contents[0]; obj.Size != meta.Size || obj.ETag != meta.ETag || !obj.LastModified.Equal(meta.LastModified) {
+		t.Errorf("ListObjects returned %+v, want the metadata of %+v", obj.ObjectMeta, meta)
+	}
+}
+
 func TestClean(t *testing.T) {
 	os.Remove(TestObjectName)
 	os.Remove(TestObjectName1)
@@ -0,0 +1,102 @@
+package bos
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+// ObjectConditions makes a read depend on the state of the object. A read whose IfMatch
+// or IfUnmodifiedSince does not hold fails with a *PreconditionFailedError; one whose
+// IfNoneMatch or IfModifiedSince does not hold, because the object has not changed,
+// fails with a *NotModifiedError. ETags may be given with or without their quotes.
+type ObjectConditions struct {
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   time.Time
+	IfUnmodifiedSince time.Time
+}
+
+func (cond *ObjectConditions) setHeaders(headers map[string]string) {
+	if cond.IfMatch != "" {
+		headers[httplib.IF_MATCH] = quoteETag(cond.IfMatch)
+	}
+	if cond.IfNoneMatch != "" {
+		headers[httplib.IF_NONE_MATCH] = quoteETag(cond.IfNoneMatch)
+	}
+	if !cond.IfModifiedSince.IsZero() {
+		headers[httplib.IF_MODIFIED_SINCE] = cond.IfModifiedSince.UTC().Format(http.TimeFormat)
+	}
+	if !cond.IfUnmodifiedSince.IsZero() {
+		headers[httplib.IF_UNMODIFIED_SINCE] = cond.IfUnmodifiedSince.UTC().Format(http.TimeFormat)
+	}
+}
+
+// quoteETag puts a bare ETag, as found in ObjectMeta, in the quotes conditional headers
+// expect. Quoted ETags, lists of them and * are left alone.
+func quoteETag(eTag string) string {
+	if eTag == "*" || strings.ContainsAny(eTag, "\",") {
+		return eTag
+	}
+	return "\"" + eTag + "\""
+}
+
+// NotModifiedError is returned for a conditional read of an object that has not
+// changed, answered with 304 Not Modified.
+type NotModifiedError struct {
+	*httplib.ErrorResponse
+
+	// ETag is the current ETag of the object, when BOS gave it.
+	ETag string
+}
+
+func (e *NotModifiedError) Error() string {
+	return fmt.Sprintf("object not modified (ETag %q)", e.ETag)
+}
+
+func (e *NotModifiedError) Unwrap() error {
+	return e.ErrorResponse
+}
+
+// PreconditionFailedError is returned for a request whose conditions, on the object or
+// on a copy source, did not hold, answered with 412 Precondition Failed.
+type PreconditionFailedError struct {
+	*httplib.ErrorResponse
+}
+
+func (e *PreconditionFailedError) Unwrap() error {
+	return e.ErrorResponse
+}
+
+// IsNotModified reports whether err is, or wraps, a *NotModifiedError.
+func IsNotModified(err error) bool {
+	var e *NotModifiedError
+	return errors.As(err, &e)
+}
+
+// IsPreconditionFailed reports whether err is, or wraps, a *PreconditionFailedError.
+func IsPreconditionFailed(err error) bool {
+	var e *PreconditionFailedError
+	return errors.As(err, &e)
+}
+
+// DoRequest sends req like httplib.Client.DoRequest, returning the answers to
+// conditional requests as *NotModifiedError and *PreconditionFailedError.
+func (c *BosClient) DoRequest(req *httplib.Request) (*http.Response, error) {
+	res, err := c.Client.DoRequest(req)
+	e, ok := err.(*httplib.ErrorResponse)
+	if !ok {
+		return res, err
+	}
+	switch e.StatusCode {
+	case http.StatusNotModified:
+		return res, &NotModifiedError{ErrorResponse: e, ETag: strings.Trim(res.Header.Get("ETag"), "\"")}
+	case http.StatusPreconditionFailed:
+		return res, &PreconditionFailedError{ErrorResponse: e}
+	}
+	return res, err
+}
@@ -0,0 +1,96 @@
+package bos
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+func TestGetObjectConditions(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "seg-001.ts", []byte("segment"), nil)
+	obj := f.object(TestBukketName, "seg-001.ts")
+	before, after := obj.lastModified.Add(-time.Hour), obj.lastModified.Add(time.Hour)
+
+	for _, tc := range []struct {
+		name string
+		cond ObjectConditions
+		want func(error) bool
+	}{
+		{"matching If-Match", ObjectConditions{IfMatch: obj.eTag}, nil},
+		{"quoted If-Match", ObjectConditions{IfMatch: "\"" + obj.eTag + "\""}, nil},
+		{"other If-Match", ObjectConditions{IfMatch: "other"}, IsPreconditionFailed},
+		{"matching If-None-Match", ObjectConditions{IfNoneMatch: obj.eTag}, IsNotModified},
+		{"other If-None-Match", ObjectConditions{IfNoneMatch: "other"}, nil},
+		{"If-Modified-Since before", ObjectConditions{IfModifiedSince: before}, nil},
+		{"If-Modified-Since after", ObjectConditions{IfModifiedSince: after}, IsNotModified},
+		{"If-Unmodified-Since after", ObjectConditions{IfUnmodifiedSince: after}, nil},
+		{"If-Unmodified-Since before", ObjectConditions{IfUnmodifiedSince: before}, IsPreconditionFailed},
+	} {
+		res, err := c.GetObjectWithOptions(TestBukketName, "seg-001.ts", &GetObjectOptions{ObjectConditions: tc.cond})
+		if tc.want == nil {
+			if err != nil {
+				t.Errorf("GetObjectWithOptions with %s failed. %v", tc.name, err)
+				continue
+			}
+			data, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if string(data) != "segment" {
+				t.Errorf("GetObjectWithOptions with %s failed. Content Not Match.", tc.name)
+			}
+		} else if !tc.want(err) {
+			t.Errorf("GetObjectWithOptions with %s returned %v", tc.name, err)
+		}
+
+		_, err = c.GetObjectMetaWithOptions(TestBukketName, "seg-001.ts", &GetObjectMetaOptions{ObjectConditions: tc.cond})
+		if (tc.want == nil && err != nil) || (tc.want != nil && !tc.want(err)) {
+			t.Errorf("GetObjectMetaWithOptions with %s returned %v", tc.name, err)
+		}
+	}
+
+	_, err := c.GetObjectWithOptions(TestBukketName, "seg-001.ts",
+		&GetObjectOptions{ObjectConditions: ObjectConditions{IfNoneMatch: obj.eTag}})
+	var notModified *NotModifiedError
+	if !errors.As(err, &notModified) || notModified.ETag != obj.eTag || notModified.StatusCode != 304 {
+		t.Errorf("GetObjectWithOptions returned %#v, want a NotModifiedError with the ETag", err)
+	}
+	var e *httplib.ErrorResponse
+	if !errors.As(err, &e) {
+		t.Errorf("NotModifiedError does not wrap the ErrorResponse")
+	}
+}
+
+func TestCopyObjectConditions(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "src.mp4", []byte("source"), nil)
+	obj := f.object(TestBukketName, "src.mp4")
+
+	for _, cond := range []CopySourceConditions{
+		{IfMatch: "other"},
+		{IfNoneMatch: obj.eTag},
+		{IfModifiedSince: obj.lastModified.Add(time.Hour)},
+		{IfUnmodifiedSince: obj.lastModified.Add(-time.Hour)},
+	} {
+		_, err := c.CopyObjectWithOptions(TestBukketName, "src.mp4", TestBukketName, "dst.mp4",
+			&CopyObjectOptions{CopySourceConditions: cond})
+		if !IsPreconditionFailed(err) {
+			t.Errorf("CopyObjectWithOptions with %+v returned %v", cond, err)
+		}
+	}
+
+	_, err := c.CopyObjectWithOptions(TestBukketName, "src.mp4", TestBukketName, "dst.mp4",
+		&CopyObjectOptions{CopySourceConditions: CopySourceConditions{
+			IfMatch:         obj.eTag,
+			IfModifiedSince: obj.lastModified.Add(-time.Hour),
+		}})
+	if err != nil {
+		t.Fatalf("CopyObjectWithOptions failed. %v", err)
+	}
+	if dst := f.object(TestBukketName, "dst.mp4"); dst == nil || !bytes.Equal(dst.data, obj.data) {
+		t.Errorf("CopyObjectWithOptions did not copy the object")
+	}
+}
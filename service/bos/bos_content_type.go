@@ -0,0 +1,59 @@
+package bos
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// Media types the players expect but which are missing from Go's builtin table and
+// from the mime.types of most minimal hosts.
+var mediaContentTypes = map[string]string{
+	".aac":  "audio/aac",
+	".flv":  "video/x-flv",
+	".m3u8": "application/vnd.apple.mpegurl",
+	".m4a":  "audio/mp4",
+	".m4s":  "video/iso.segment",
+	".m4v":  "video/x-m4v",
+	".mkv":  "video/x-matroska",
+	".mov":  "video/quicktime",
+	".mp3":  "audio/mpeg",
+	".mp4":  "video/mp4",
+	".mpd":  "application/dash+xml",
+	".srt":  "application/x-subrip",
+	".ts":   "video/mp2t",
+	".vtt":  "text/vtt",
+	".webm": "video/webm",
+}
+
+// contentTypeByExtension returns the content type registered for the extension of
+// objectName, or "" if it is unknown.
+func contentTypeByExtension(objectName string) string {
+	ext := strings.ToLower(path.Ext(objectName))
+	if ext == "" {
+		return ""
+	}
+	if t, ok := mediaContentTypes[ext]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// detectContentType guesses the content type of an object from its name and, failing
+// that, from the first bytes of body. The returned reader must be used in place of
+// body since sniffing consumes from it.
+func detectContentType(objectName string, body io.Reader) (string, io.Reader) {
+	if t := contentTypeByExtension(objectName); t != "" {
+		return t, body
+	}
+
+	br := bufio.NewReaderSize(body, sniffLen)
+	head, _ := br.Peek(sniffLen)
+	return http.DetectContentType(head), br
+}
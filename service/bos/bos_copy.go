@@ -0,0 +1,140 @@
+package bos
+
+import (
+	"context"
+	"strings"
+)
+
+// partCopySource is the range of an existing object that a part is copied from.
+type partCopySource struct {
+	bucketName string
+	objectName string
+	start      int64
+	cond       CopySourceConditions
+	sse        *ServerSideEncryption
+}
+
+func (m *multipartUpload) copyPart(job *uploadPartJob) (string, error) {
+	src := job.copy
+	res, err := m.uploader.Client.UploadPartCopy(src.bucketName, src.objectName, m.bucketName, m.objectName,
+		m.uploadId, job.number, &ObjectRange{Start: src.start, End: src.start + job.size - 1},
+		&UploadPartCopyOptions{CopySourceConditions: src.cond, SourceEncryption: src.sse, Encryption: m.sse})
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(res.ETag, "\""), nil
+}
+
+// CopyLargeObject copies an object of any size within BOS. Objects of up to
+// MaxSingleCopySize are copied with CopyObjectWithOptions; larger ones are split into
+// PartSize parts and copied with UploadPartCopy, Concurrency parts at a time.
+//
+// A multipart copy starts a new object, so with MetadataDirectiveCopy the source's
+// headers and user metadata are read up front and applied to it, keeping the result the
+// same as a single copy. Every part is copied on condition that the source still has the
+// ETag it had when the copy started, unless opts sets IfMatch itself.
+func (u *Uploader) CopyLargeObject(srcBucketName, srcObjectName, destBucketName, destObjectName string,
+	opts *CopyObjectOptions) (*UploadResult, error) {
+
+	if opts == nil {
+		opts = &CopyObjectOptions{}
+	}
+	srcObjectName = u.Client.formatPath(srcObjectName)
+	destObjectName = u.Client.formatPath(destObjectName)
+
+	headers := map[string]string{}
+	opts.SourceEncryption.customerKeyOnly().setHeaders(headers)
+	header, err := u.Client.headObject(srcBucketName, srcObjectName, headers)
+	if err != nil {
+		return nil, err
+	}
+	src, err := parseObjectMeta(header)
+	if err != nil {
+		return nil, err
+	}
+	size := src.Size
+	if size <= u.maxSingleCopySize() {
+		res, err := u.Client.CopyObjectWithOptions(srcBucketName, srcObjectName, destBucketName, destObjectName, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &UploadResult{
+			BucketName: destBucketName,
+			ObjectName: destObjectName,
+			ETag:       strings.Trim(res.ETag, "\""),
+			Size:       size,
+		}, nil
+	}
+
+	partSize, err := u.partSize(size)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &PutObjectOptions{}
+	if opts.MetadataDirective == MetadataDirectiveReplace {
+		if opts.Meta != nil {
+			*meta = *opts.Meta
+		}
+	} else {
+		meta = sourceObjectOptions(src)
+		if opts.Meta != nil {
+			if opts.Meta.StorageClass != "" {
+				meta.StorageClass = opts.Meta.StorageClass
+			}
+			meta.CannedAcl = opts.Meta.CannedAcl
+			meta.Encryption = opts.Meta.Encryption
+		}
+	}
+	init, err := u.Client.InitiateMultipartUploadWithOptions(destBucketName, destObjectName, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	cond := opts.CopySourceConditions
+	if cond.IfMatch == "" {
+		cond.IfMatch = src.ETag
+	}
+	var jobs []*uploadPartJob
+	for number, offset := 1, int64(0); offset < size; number, offset = number+1, offset+partSize {
+		n := partSize
+		if size-offset < n {
+			n = size - offset
+		}
+		jobs = append(jobs, &uploadPartJob{
+			number: number,
+			size:   n,
+			copy: &partCopySource{
+				bucketName: srcBucketName,
+				objectName: srcObjectName,
+				start:      offset,
+				cond:       cond,
+				sse:        opts.SourceEncryption,
+			},
+		})
+	}
+
+	m := u.newMultipartUpload(context.Background(), destBucketName, destObjectName, init.UploadId)
+	m.sse = meta.Encryption
+	return m.run(jobs, size)
+}
+
+// sourceObjectOptions recovers the headers an object was stored with from its metadata.
+func sourceObjectOptions(meta *ObjectMeta) *PutObjectOptions {
+	return &PutObjectOptions{
+		ContentType:        meta.ContentType,
+		ContentDisposition: meta.ContentDisposition,
+		ContentEncoding:    meta.ContentEncoding,
+		CacheControl:       meta.CacheControl,
+		Expires:            meta.Expires,
+		StorageClass:       meta.StorageClass,
+		UserMeta:           meta.UserMeta,
+	}
+}
+
+func (u *Uploader) maxSingleCopySize() int64 {
+	if u.singleCopyLimit > 0 {
+		return u.singleCopyLimit
+	}
+	return MaxSingleCopySize
+}
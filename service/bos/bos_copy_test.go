@@ -0,0 +1,145 @@
+package bos
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+)
+
+func TestCopyObjectWithOptions(t *testing.T) {
+	f, c := newFakeBos(t)
+	src := f.putObject(TestBukketName, "masters/a.mov", []byte("master"), http.Header{
+		"Content-Type":     {"video/quicktime"},
+		"X-Bce-Meta-Title": {"a"},
+	})
+
+	res, err := c.CopyObjectWithOptions(TestBukketName, "masters/a.mov", "archive", "a.mov", &CopyObjectOptions{
+		Meta: &PutObjectOptions{StorageClass: StorageClassArchive, ContentType: "ignored/type"},
+	})
+	if err != nil || res.ETag != src.eTag {
+		t.Fatalf("CopyObjectWithOptions failed. %v %+v", err, res)
+	}
+	obj := f.object("archive", "a.mov")
+	if obj.header.Get("Content-Type") != "video/quicktime" || obj.header.Get("X-Bce-Meta-Title") != "a" ||
+		obj.header.Get(auth.BCE_STORAGE_CLASS) != StorageClassArchive {
+		t.Errorf("CopyObjectWithOptions did not keep the source metadata. %v", obj.header)
+	}
+
+	_, err = c.CopyObjectWithOptions(TestBukketName, "masters/a.mov", "archive", "b.mov", &CopyObjectOptions{
+		MetadataDirective: MetadataDirectiveReplace,
+		Meta:              &PutObjectOptions{ContentType: "video/mp4", UserMeta: map[string]string{"title": "b"}},
+	})
+	if err != nil {
+		t.Fatalf("CopyObjectWithOptions failed. %v", err)
+	}
+	obj = f.object("archive", "b.mov")
+	if obj.header.Get("Content-Type") != "video/mp4" || obj.header.Get("X-Bce-Meta-Title") != "b" {
+		t.Errorf("CopyObjectWithOptions did not replace the metadata. %v", obj.header)
+	}
+
+	for _, cond := range []CopySourceConditions{
+		{IfMatch: "0123"},
+		{IfNoneMatch: src.eTag},
+		{IfModifiedSince: src.lastModified},
+		{IfUnmodifiedSince: src.lastModified.Add(-time.Hour)},
+	} {
+		if _, err = c.CopyObjectWithOptions(TestBukketName, "masters/a.mov", "archive", "c.mov",
+			&CopyObjectOptions{CopySourceConditions: cond}); err == nil {
+			t.Errorf("CopyObjectWithOptions should fail with %+v", cond)
+		}
+	}
+	if _, err = c.CopyObjectWithOptions(TestBukketName, "masters/a.mov", "archive", "c.mov",
+		&CopyObjectOptions{CopySourceConditions: CopySourceConditions{
+			IfMatch:           src.eTag,
+			IfModifiedSince:   src.lastModified.Add(-time.Hour),
+			IfUnmodifiedSince: src.lastModified,
+		}}); err != nil {
+		t.Errorf("CopyObjectWithOptions failed with matching conditions. %v", err)
+	}
+}
+
+func TestCopyLargeObject(t *testing.T) {
+	f, c := newFakeBos(t)
+	content := randomContent(t, 2*MinPartSize+1000)
+	src := f.putObject(TestBukketName, "masters/big.mov", content, http.Header{
+		"Content-Type":     {"video/quicktime"},
+		"X-Bce-Meta-Title": {"big"},
+	})
+
+	u := NewUploader(c)
+	u.PartSize = MinPartSize
+	u.singleCopyLimit = MinPartSize
+	res, err := u.CopyLargeObject(TestBukketName, "masters/big.mov", "archive", "big.mov", nil)
+	if err != nil {
+		t.Fatalf("CopyLargeObject failed. %v", err)
+	}
+	if res.UploadId == "" || res.Size != int64(len(content)) {
+		t.Errorf("CopyLargeObject did not use a multipart copy. %+v", res)
+	}
+	obj := f.object("archive", "big.mov")
+	if obj == nil || !bytes.Equal(obj.data, content) {
+		t.Fatalf("CopyLargeObject failed. Content Not Match.")
+	}
+	if obj.header.Get("Content-Type") != "video/quicktime" || obj.header.Get("X-Bce-Meta-Title") != "big" {
+		t.Errorf("CopyLargeObject did not keep the source metadata. %v", obj.header)
+	}
+
+	f.mu.Lock()
+	copies := 0
+	for _, r := range f.requests {
+		if r.Header.Get(auth.BCE_COPY_SOURCE_RANGE) != "" {
+			copies++
+			if r.Header.Get(auth.BCE_COPY_SOURCE_IF_MATCH) != "\""+src.eTag+"\"" {
+				t.Errorf("CopyLargeObject part copy not pinned to the source ETag")
+			}
+		}
+	}
+	f.mu.Unlock()
+	if copies != 3 {
+		t.Errorf("CopyLargeObject sent %d part copies, want 3", copies)
+	}
+
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get(auth.BCE_COPY_SOURCE_RANGE) == "bytes=0-5242879" {
+			f.putObject(TestBukketName, "masters/big.mov", randomContent(t, len(content)), nil)
+		}
+		return false
+	}
+	u.Concurrency = 1
+	if _, err = u.CopyLargeObject(TestBukketName, "masters/big.mov", "archive", "big2.mov", nil); err == nil {
+		t.Errorf("CopyLargeObject should fail when the source changes")
+	}
+	f.mu.Lock()
+	uploads := len(f.uploads)
+	f.mu.Unlock()
+	if uploads != 0 {
+		t.Errorf("CopyLargeObject left %d uploads behind", uploads)
+	}
+}
+
+func TestCopyLargeObjectSmall(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "small.mp4", []byte("small"), nil)
+
+	res, err := NewUploader(c).CopyLargeObject(TestBukketName, "small.mp4", TestBukketName, "copy.mp4", nil)
+	if err != nil || res.UploadId != "" || res.Size != 5 {
+		t.Errorf("CopyLargeObject of a small object failed. %v %+v", err, res)
+	}
+
+	// Objects larger than a part but within MaxSingleCopySize are still copied at once,
+	// keeping their ETag.
+	src := f.putObject(TestBukketName, "medium.mp4", randomContent(t, MinPartSize+1000), nil)
+	u := NewUploader(c)
+	u.PartSize = MinPartSize
+	res, err = u.CopyLargeObject(TestBukketName, "medium.mp4", TestBukketName, "medium-copy.mp4", nil)
+	if err != nil || res.UploadId != "" || res.ETag != src.eTag {
+		t.Errorf("CopyLargeObject of an object larger than a part returned %+v, %v", res, err)
+	}
+	if _, err = c.UploadPartCopy(TestBukketName, "small.mp4", TestBukketName, "copy.mp4", "id", 1,
+		&ObjectRange{Start: 1, End: -1}, nil); err == nil {
+		t.Errorf("UploadPartCopy should reject an open-ended range")
+	}
+}
@@ -0,0 +1,233 @@
+package bos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+// MaxCorsRules is the largest number of rules a bucket's CORS configuration may hold.
+const MaxCorsRules = 100
+
+// CorsRule allows browsers on AllowedOrigins to make the AllowedMethods requests to a
+// bucket. Origins and headers may contain one * wildcard, as in "https://*.example.com".
+type CorsRule struct {
+	AllowedOrigins       []string `json:"allowedOrigins"`
+	AllowedMethods       []string `json:"allowedMethods"`
+	AllowedHeaders       []string `json:"allowedHeaders,omitempty"`
+	AllowedExposeHeaders []string `json:"allowedExposeHeaders,omitempty"`
+	MaxAgeSeconds        int      `json:"maxAgeSeconds,omitempty"`
+}
+
+var corsMethods = map[string]bool{
+	httplib.GET:    true,
+	httplib.PUT:    true,
+	httplib.POST:   true,
+	httplib.DELETE: true,
+	httplib.HEAD:   true,
+}
+
+// ValidateCorsRules checks rules for mistakes BOS would reject.
+func ValidateCorsRules(rules []CorsRule) error {
+	if len(rules) == 0 || len(rules) > MaxCorsRules {
+		return fmt.Errorf("CORS configuration needs 1 to %d rules, not %d", MaxCorsRules, len(rules))
+	}
+	for i, r := range rules {
+		if len(r.AllowedOrigins) == 0 {
+			return fmt.Errorf("CORS rule %d allows no origins", i)
+		}
+		for _, o := range r.AllowedOrigins {
+			if strings.Count(o, "*") > 1 {
+				return fmt.Errorf("CORS rule %d: origin %q has more than one wildcard", i, o)
+			}
+		}
+		if len(r.AllowedMethods) == 0 {
+			return fmt.Errorf("CORS rule %d allows no methods", i)
+		}
+		for _, m := range r.AllowedMethods {
+			if !corsMethods[m] {
+				return fmt.Errorf("CORS rule %d: method %q is not one of GET, PUT, POST, DELETE and HEAD", i, m)
+			}
+		}
+		for _, h := range r.AllowedHeaders {
+			if strings.Count(h, "*") > 1 {
+				return fmt.Errorf("CORS rule %d: header %q has more than one wildcard", i, h)
+			}
+		}
+		if r.MaxAgeSeconds < 0 {
+			return fmt.Errorf("CORS rule %d: negative max age", i)
+		}
+	}
+	return nil
+}
+
+// CorsPreflightResult holds the headers BOS answers a successful preflight with.
+type CorsPreflightResult struct {
+	AllowOrigin   string
+	AllowMethods  []string
+	AllowHeaders  []string
+	ExposeHeaders []string
+	MaxAgeSeconds int
+}
+
+// EvaluateCorsPreflight works out locally how BOS answers the preflight (OPTIONS)
+// request a browser sends before a cross-origin request from origin, with the given
+// method and headers. The first rule allowing all three applies; ok is false when none
+// does and the browser would block the request.
+func EvaluateCorsPreflight(rules []CorsRule, origin, method string, requestHeaders []string) (
+	result *CorsPreflightResult, ok bool) {
+
+	for _, r := range rules {
+		if !corsRuleAllows(r, origin, method, requestHeaders) {
+			continue
+		}
+		result = &CorsPreflightResult{
+			AllowOrigin:   origin,
+			AllowMethods:  r.AllowedMethods,
+			ExposeHeaders: r.AllowedExposeHeaders,
+			MaxAgeSeconds: r.MaxAgeSeconds,
+		}
+		for _, h := range requestHeaders {
+			result.AllowHeaders = append(result.AllowHeaders, strings.ToLower(strings.TrimSpace(h)))
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func corsRuleAllows(r CorsRule, origin, method string, requestHeaders []string) bool {
+	matched := false
+	for _, o := range r.AllowedOrigins {
+		if wildcardMatch(o, origin) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	matched = false
+	for _, m := range r.AllowedMethods {
+		if m == method {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	for _, h := range requestHeaders {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		matched = false
+		for _, allowed := range r.AllowedHeaders {
+			if wildcardMatch(strings.ToLower(allowed), h) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// wildcardMatch reports whether s matches pattern, in which one * stands for any run of
+// characters.
+func wildcardMatch(pattern, s string) bool {
+	i := strings.Index(pattern, "*")
+	if i < 0 {
+		return pattern == s
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(s) >= len(prefix)+len(suffix) && strings.HasPrefix(s, prefix) && strings.HasSuffix(s, suffix)
+}
+
+/*
+ * Name: PutBucketCors
+ * URL: http://bce.baidu.com/doc/BOS/API.html#PutBucketCors.E6.8E.A5.E5.8F.A3
+ */
+
+// PutBucketCors replaces the CORS configuration of a bucket, after checking it with
+// ValidateCorsRules.
+func (c *BosClient) PutBucketCors(bucketName string, rules []CorsRule) (err error) {
+	if err = ValidateCorsRules(rules); err != nil {
+		return
+	}
+	jstring, err := json.Marshal(map[string][]CorsRule{"corsConfiguration": rules})
+	if err != nil {
+		return
+	}
+
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{},
+		Query:   "cors",
+		Path:    c.APIVersion + "/" + bucketName,
+		Body:    bytes.NewReader(jstring),
+		Type:    httplib.JSON,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+/*
+ * Name: GetBucketCors
+ * URL: http://bce.baidu.com/doc/BOS/API.html#GetBucketCors.E6.8E.A5.E5.8F.A3
+ */
+
+type BucketCorsResponse struct {
+	CorsConfiguration []CorsRule `json:"corsConfiguration"`
+}
+
+func (c *BosClient) GetBucketCors(bucketName string) (output *BucketCorsResponse, err error) {
+	req := &httplib.Request{
+		Method:  httplib.GET,
+		Headers: map[string]string{},
+		Query:   "cors",
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response BucketCorsResponse
+
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	return &response, nil
+}
+
+/*
+ * Name: DeleteBucketCors
+ * URL: http://bce.baidu.com/doc/BOS/API.html#DeleteBucketCors.E6.8E.A5.E5.8F.A3
+ */
+
+func (c *BosClient) DeleteBucketCors(bucketName string) (err error) {
+	req := &httplib.Request{
+		Method:  httplib.DELETE,
+		Headers: map[string]string{},
+		Query:   "cors",
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
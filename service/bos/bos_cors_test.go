@@ -0,0 +1,98 @@
+package bos
+
+import (
+	"reflect"
+	"testing"
+)
+
+// uploaderCorsRules lets the web uploader PUT from any subdomain of example.com, and
+// anyone GET.
+func uploaderCorsRules() []CorsRule {
+	return []CorsRule{
+		{
+			AllowedOrigins:       []string{"https://*.example.com"},
+			AllowedMethods:       []string{"PUT", "POST", "GET"},
+			AllowedHeaders:       []string{"Content-Type", "x-bce-*", "Authorization"},
+			AllowedExposeHeaders: []string{"ETag"},
+			MaxAgeSeconds:        600,
+		},
+		{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "HEAD"},
+		},
+	}
+}
+
+func TestBucketCors(t *testing.T) {
+	_, c := newFakeBos(t)
+
+	rules := uploaderCorsRules()
+	if err := c.PutBucketCors(TestBukketName, rules); err != nil {
+		t.Fatalf("PutBucketCors failed. %v", err)
+	}
+	res, err := c.GetBucketCors(TestBukketName)
+	if err != nil || !reflect.DeepEqual(res.CorsConfiguration, rules) {
+		t.Errorf("GetBucketCors failed. %v %+v", err, res)
+	}
+	if err = c.DeleteBucketCors(TestBukketName); err != nil {
+		t.Fatalf("DeleteBucketCors failed. %v", err)
+	}
+	if _, err = c.GetBucketCors(TestBukketName); err == nil {
+		t.Errorf("GetBucketCors should fail after DeleteBucketCors")
+	}
+
+	if err = c.PutBucketCors(TestBukketName, []CorsRule{{AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"OPTIONS"}}}); err == nil {
+		t.Errorf("PutBucketCors should reject an unsupported method")
+	}
+}
+
+func TestEvaluateCorsPreflight(t *testing.T) {
+	rules := uploaderCorsRules()
+	for _, tc := range []struct {
+		origin, method string
+		headers        []string
+		ok             bool
+		maxAge         int
+	}{
+		{"https://upload.example.com", "PUT", []string{"Content-Type", "X-Bce-Date"}, true, 600},
+		{"https://upload.example.com", "PUT", nil, true, 600},
+		{"https://upload.example.com", "PUT", []string{"X-Requested-With"}, false, 0},
+		{"https://upload.example.com", "DELETE", nil, false, 0},
+		{"http://upload.example.com", "PUT", nil, false, 0},
+		{"https://example.com.evil.org", "PUT", nil, false, 0},
+		{"https://player.other.org", "GET", nil, true, 0},
+		{"https://player.other.org", "GET", []string{"Range"}, false, 0},
+	} {
+		res, ok := EvaluateCorsPreflight(rules, tc.origin, tc.method, tc.headers)
+		if ok != tc.ok {
+			t.Errorf("EvaluateCorsPreflight(%s %s %v) = %v, want %v", tc.method, tc.origin, tc.headers, ok, tc.ok)
+			continue
+		}
+		if ok && (res.AllowOrigin != tc.origin || res.MaxAgeSeconds != tc.maxAge ||
+			len(res.AllowHeaders) != len(tc.headers)) {
+			t.Errorf("EvaluateCorsPreflight(%s %s %v) = %+v", tc.method, tc.origin, tc.headers, res)
+		}
+	}
+}
+
+func TestValidateCorsRules(t *testing.T) {
+	if err := ValidateCorsRules(uploaderCorsRules()); err != nil {
+		t.Fatalf("ValidateCorsRules rejected valid rules. %v", err)
+	}
+	for name, r := range map[string]CorsRule{
+		"no origins":      {AllowedMethods: []string{"GET"}},
+		"two wildcards":   {AllowedOrigins: []string{"https://*.*.com"}, AllowedMethods: []string{"GET"}},
+		"no methods":      {AllowedOrigins: []string{"*"}},
+		"lower method":    {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"get"}},
+		"header patterns": {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowedHeaders: []string{"*-*"}},
+		"negative age":    {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, MaxAgeSeconds: -1},
+	} {
+		if err := ValidateCorsRules([]CorsRule{r}); err == nil {
+			t.Errorf("ValidateCorsRules accepted a rule with %s", name)
+		}
+	}
+	if err := ValidateCorsRules(nil); err == nil {
+		t.Errorf("ValidateCorsRules accepted no rules")
+	}
+}
@@ -0,0 +1,504 @@
+package bos
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// Client-side encryption schemes. GCM authenticates the content but has to hold a whole
+// object in memory to encrypt or fully read it; CTR streams, and can be encrypted part
+// by part for multipart uploads.
+const (
+	CryptoSchemeAESGCM = "AES/GCM/NoPadding"
+	CryptoSchemeAESCTR = "AES/CTR/NoPadding"
+)
+
+// User metadata, without the x-bce-meta- prefix, recording how an object was encrypted
+// on the client.
+const (
+	cryptoMetaKey    = "client-side-encryption-key"
+	cryptoMetaIV     = "client-side-encryption-iv"
+	cryptoMetaScheme = "client-side-encryption-scheme"
+	cryptoMetaWrap   = "client-side-encryption-wrap"
+
+	// cryptoMetaLength is the size of the content before encryption, which differs from
+	// the object size under GCM.
+	cryptoMetaLength = "client-side-encryption-unencrypted-content-length"
+)
+
+const dataKeySize = 32
+
+// KeyWrapper protects the data keys objects are encrypted with. Each object gets a new
+// random data key, which is stored with the object in wrapped form.
+type KeyWrapper interface {
+	// Algorithm names the wrapping, and is stored with the object so that it is not
+	// unwrapped with another.
+	Algorithm() string
+	WrapKey(dataKey []byte) ([]byte, error)
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// Key wrapping algorithms of the wrappers provided here.
+const (
+	KeyWrapAlgorithmAESGCM  = "AES/GCM"
+	KeyWrapAlgorithmRSAOAEP = "RSA/OAEP-SHA256"
+)
+
+type aesKeyWrapper struct {
+	aead cipher.AEAD
+}
+
+// NewAESKeyWrapper returns a KeyWrapper sealing data keys with AES-GCM under masterKey,
+// which must be 16, 24 or 32 bytes long.
+func NewAESKeyWrapper(masterKey []byte) (KeyWrapper, error) {
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesKeyWrapper{aead: aead}, nil
+}
+
+func (w *aesKeyWrapper) Algorithm() string {
+	return KeyWrapAlgorithmAESGCM
+}
+
+func (w *aesKeyWrapper) WrapKey(dataKey []byte) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return w.aead.Seal(nonce, nonce, dataKey, nil), nil
+}
+
+func (w *aesKeyWrapper) UnwrapKey(wrapped []byte) ([]byte, error) {
+	if len(wrapped) < w.aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	n := w.aead.NonceSize()
+	return w.aead.Open(nil, wrapped[:n], wrapped[n:], nil)
+}
+
+type rsaKeyWrapper struct {
+	pub  *rsa.PublicKey
+	priv *rsa.PrivateKey
+}
+
+// NewRSAKeyWrapper returns a KeyWrapper encrypting data keys with RSA-OAEP. priv may be
+// nil for a client that only writes objects.
+func NewRSAKeyWrapper(pub *rsa.PublicKey, priv *rsa.PrivateKey) KeyWrapper {
+	if pub == nil && priv != nil {
+		pub = &priv.PublicKey
+	}
+	return &rsaKeyWrapper{pub: pub, priv: priv}
+}
+
+func (w *rsaKeyWrapper) Algorithm() string {
+	return KeyWrapAlgorithmRSAOAEP
+}
+
+func (w *rsaKeyWrapper) WrapKey(dataKey []byte) ([]byte, error) {
+	return rsa.EncryptOAEP(sha256.New(), rand.Reader, w.pub, dataKey, nil)
+}
+
+func (w *rsaKeyWrapper) UnwrapKey(wrapped []byte) ([]byte, error) {
+	if w.priv == nil {
+		return nil, fmt.Errorf("RSA key wrapper has no private key to unwrap with")
+	}
+	return rsa.DecryptOAEP(sha256.New(), rand.Reader, w.priv, wrapped, nil)
+}
+
+// envelope holds the cipher an object's content is encrypted with.
+type envelope struct {
+	scheme string
+	block  cipher.Block
+	iv     []byte
+}
+
+// stream returns the keystream of the envelope from the given content offset on. GCM
+// encrypts with CTR, starting at counter 2, so it is read the same way.
+func (env *envelope) stream(offset int64) cipher.Stream {
+	ctr := make([]byte, aes.BlockSize)
+	copy(ctr, env.iv)
+	if env.scheme == CryptoSchemeAESGCM {
+		ctr[aes.BlockSize-1] = 2
+	}
+	n, carry := uint64(offset/aes.BlockSize), uint64(0)
+	for i := aes.BlockSize - 1; i >= 0; i-- {
+		sum := uint64(ctr[i]) + n&0xff + carry
+		ctr[i] = byte(sum)
+		carry, n = sum>>8, n>>8
+	}
+
+	s := cipher.NewCTR(env.block, ctr)
+	if skip := offset % aes.BlockSize; skip > 0 {
+		buf := make([]byte, skip)
+		s.XORKeyStream(buf, buf)
+	}
+	return s
+}
+
+// encryptingReaderAt encrypts r with a CTR envelope, at any offset.
+type encryptingReaderAt struct {
+	r   io.ReaderAt
+	env *envelope
+}
+
+func (x *encryptingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := x.r.ReadAt(p, off)
+	x.env.stream(off).XORKeyStream(p[:n], p[:n])
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// EncryptionClient stores objects encrypted on the client, so that BOS only ever sees
+// ciphertext. Each object is encrypted with a random data key, kept in its user
+// metadata wrapped by Wrapper along with the IV; reads through the client unwrap it and
+// decrypt transparently.
+//
+// Only the methods of EncryptionClient encrypt. Objects written through Client, or any
+// other BosClient method, are stored as sent.
+type EncryptionClient struct {
+	Client  *BosClient
+	Wrapper KeyWrapper
+
+	// Scheme is what PutObjectWithOptions encrypts with. Upload always uses CTR.
+	Scheme string
+
+	// Uploader sends the objects given to Upload and UploadFile.
+	Uploader *Uploader
+}
+
+func NewEncryptionClient(c *BosClient, wrapper KeyWrapper) *EncryptionClient {
+	return &EncryptionClient{
+		Client:   c,
+		Wrapper:  wrapper,
+		Scheme:   CryptoSchemeAESGCM,
+		Uploader: NewUploader(c),
+	}
+}
+
+// newEnvelope creates the envelope for a new object and the user metadata recording it.
+func (e *EncryptionClient) newEnvelope(scheme string) (*envelope, map[string]string, error) {
+	ivSize := aes.BlockSize
+	switch scheme {
+	case CryptoSchemeAESGCM:
+		ivSize = 12
+	case CryptoSchemeAESCTR:
+	default:
+		return nil, nil, fmt.Errorf("unknown client-side encryption scheme %q", scheme)
+	}
+
+	key := make([]byte, dataKeySize)
+	iv := make([]byte, ivSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := e.Wrapper.WrapKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := map[string]string{
+		cryptoMetaKey:    base64.StdEncoding.EncodeToString(wrapped),
+		cryptoMetaIV:     base64.StdEncoding.EncodeToString(iv),
+		cryptoMetaScheme: scheme,
+		cryptoMetaWrap:   e.Wrapper.Algorithm(),
+	}
+	return &envelope{scheme: scheme, block: block, iv: iv}, meta, nil
+}
+
+// openEnvelope recovers the envelope of an object from its user metadata. It returns
+// nil for an object not encrypted on the client, and the unencrypted size, or -1 when
+// it was not recorded.
+func (e *EncryptionClient) openEnvelope(meta map[string]string) (env *envelope, size int64, err error) {
+	scheme := meta[cryptoMetaScheme]
+	if scheme == "" {
+		return nil, -1, nil
+	}
+	if scheme != CryptoSchemeAESGCM && scheme != CryptoSchemeAESCTR {
+		return nil, -1, fmt.Errorf("unknown client-side encryption scheme %q", scheme)
+	}
+	if wrap := meta[cryptoMetaWrap]; wrap != e.Wrapper.Algorithm() {
+		return nil, -1, fmt.Errorf("object key is wrapped with %q, not %q", wrap, e.Wrapper.Algorithm())
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(meta[cryptoMetaKey])
+	if err != nil {
+		return nil, -1, fmt.Errorf("invalid wrapped object key: %v", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(meta[cryptoMetaIV])
+	if err != nil {
+		return nil, -1, fmt.Errorf("invalid object IV: %v", err)
+	}
+	key, err := e.Wrapper.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, -1, fmt.Errorf("unwrap object key: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, -1, err
+	}
+	if (scheme == CryptoSchemeAESGCM && len(iv) != 12) || (scheme == CryptoSchemeAESCTR && len(iv) != aes.BlockSize) {
+		return nil, -1, fmt.Errorf("object IV has the wrong length for %s", scheme)
+	}
+
+	size = -1
+	if s := meta[cryptoMetaLength]; s != "" {
+		if size, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, -1, fmt.Errorf("invalid unencrypted content length %q", s)
+		}
+	}
+	return &envelope{scheme: scheme, block: block, iv: iv}, size, nil
+}
+
+// encryptedOptions returns opts for the ciphertext: the checksums of the plain content
+// are dropped, and the envelope metadata added to the user metadata.
+func encryptedOptions(opts *PutObjectOptions, meta map[string]string) *PutObjectOptions {
+	o := *opts
+	o.ContentMD5, o.ContentSHA256, o.ContentCRC32 = "", "", ""
+	o.UserMeta = map[string]string{}
+	for k, v := range opts.UserMeta {
+		o.UserMeta[k] = v
+	}
+	for k, v := range meta {
+		o.UserMeta[k] = v
+	}
+	return &o
+}
+
+// PutObjectWithOptions encrypts body with Scheme and stores it. Content checksums in
+// opts are ignored, as BOS only sees the ciphertext. Under GCM the whole body is read
+// into memory first.
+func (e *EncryptionClient) PutObjectWithOptions(bucketName, objectName string, body io.Reader,
+	opts *PutObjectOptions) (eTag string, err error) {
+
+	if opts == nil {
+		opts = &PutObjectOptions{}
+	}
+	if body == nil {
+		body = bytes.NewReader(nil)
+	}
+	scheme := e.Scheme
+	if scheme == "" {
+		scheme = CryptoSchemeAESGCM
+	}
+	env, meta, err := e.newEnvelope(scheme)
+	if err != nil {
+		return "", err
+	}
+	o := encryptedOptions(opts, meta)
+
+	size := opts.ContentLength
+	if size > 0 {
+		body = io.LimitReader(body, size)
+	} else if n, ok := readerLength(body); ok && scheme == CryptoSchemeAESCTR {
+		size = n
+	} else {
+		content, err := ioutil.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		body, size = bytes.NewReader(content), int64(len(content))
+	}
+	if o.ContentType == "" {
+		o.ContentType, body = detectContentType(objectName, body)
+	}
+
+	if scheme == CryptoSchemeAESCTR {
+		o.ContentLength = size
+		return e.Client.PutObjectWithOptions(bucketName, objectName, cipher.StreamReader{S: env.stream(0), R: body}, o)
+	}
+
+	plain, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	aead, err := cipher.NewGCM(env.block)
+	if err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nil, env.iv, plain, nil)
+	o.UserMeta[cryptoMetaLength] = strconv.Itoa(len(plain))
+	o.ContentLength = int64(len(sealed))
+	return e.Client.PutObjectWithOptions(bucketName, objectName, bytes.NewReader(sealed), o)
+}
+
+// UploadFile encrypts the named local file and uploads it with Uploader.
+func (e *EncryptionClient) UploadFile(bucketName, objectName, fileName string, opts *PutObjectOptions) (*UploadResult, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return e.Upload(bucketName, objectName, file, opts)
+}
+
+// Upload encrypts body with CTR and uploads it with Uploader, in parts when it is large.
+// As with Uploader.Upload, an io.ReaderAt of known size is read and encrypted
+// concurrently. UploadResult.Size is the size of the ciphertext, which under CTR is
+// that of body.
+func (e *EncryptionClient) Upload(bucketName, objectName string, body io.Reader, opts *PutObjectOptions) (*UploadResult, error) {
+	if opts == nil {
+		opts = &PutObjectOptions{}
+	}
+	env, meta, err := e.newEnvelope(CryptoSchemeAESCTR)
+	if err != nil {
+		return nil, err
+	}
+	o := encryptedOptions(opts, meta)
+
+	if ra, ok := body.(io.ReaderAt); ok {
+		if size, ok := readerLength(body); ok {
+			offset := int64(0)
+			if s, ok := body.(io.Seeker); ok {
+				offset, _ = s.Seek(0, io.SeekCurrent)
+			}
+			plain := io.NewSectionReader(ra, offset, size)
+			if o.ContentType == "" {
+				head := make([]byte, sniffLen)
+				n, _ := plain.ReadAt(head, 0)
+				o.ContentType, _ = detectContentType(objectName, bytes.NewReader(head[:n]))
+			}
+			sealed := io.NewSectionReader(&encryptingReaderAt{r: plain, env: env}, 0, size)
+			return e.Uploader.Upload(bucketName, objectName, sealed, o)
+		}
+	}
+
+	if o.ContentType == "" {
+		o.ContentType, body = detectContentType(objectName, body)
+	}
+	return e.Uploader.Upload(bucketName, objectName, cipher.StreamReader{S: env.stream(0), R: body}, o)
+}
+
+// GetObject reads an object like BosClient.GetObject, decrypting it.
+func (e *EncryptionClient) GetObject(bucketName, objectName string, startPos, endPos int64) (output GetObjectResponse, err error) {
+	opts := &GetObjectOptions{}
+	if startPos >= 0 && endPos > 0 {
+		if endPos > startPos {
+			opts.Range = &ObjectRange{Start: startPos, End: endPos}
+		} else {
+			opts.Range = &ObjectRange{Start: startPos, End: -1}
+		}
+	}
+	return e.GetObjectWithOptions(bucketName, objectName, opts)
+}
+
+// GetObjectWithOptions reads an object and decrypts it, with Range, Size and
+// ContentLength counting bytes of the unencrypted content. Objects not encrypted on
+// the client are returned as stored.
+//
+// A full read of a GCM object is authenticated, and so held in memory before it is
+// returned. Ranged reads stream and are not authenticated: GCM is decrypted as the
+// CTR it is built on, as it has to be without the rest of the object.
+func (e *EncryptionClient) GetObjectWithOptions(bucketName, objectName string,
+	opts *GetObjectOptions) (output GetObjectResponse, err error) {
+
+	if opts == nil {
+		opts = &GetObjectOptions{}
+	}
+
+	// Ranges start on a cipher block, for the keystream to be picked up there.
+	o := *opts
+	skip := int64(0)
+	if opts.Range != nil {
+		if err = opts.Range.validate(); err != nil {
+			return
+		}
+		skip = opts.Range.Start % aes.BlockSize
+		o.Range = &ObjectRange{Start: opts.Range.Start - skip, End: opts.Range.End}
+	}
+
+	output, err = e.Client.GetObjectWithOptions(bucketName, objectName, &o)
+	if err != nil {
+		return
+	}
+	env, size, err := e.openEnvelope(output.UserMeta)
+	if err != nil {
+		output.Body.Close()
+		return output, err
+	}
+
+	if o.Range == nil {
+		if env != nil && env.scheme == CryptoSchemeAESGCM {
+			err = openGCM(env, &output)
+		} else if env != nil {
+			output.Body = readCloser{cipher.StreamReader{S: env.stream(0), R: output.Body}, output.Body}
+		}
+		return output, err
+	}
+
+	start, n := o.Range.Start, output.ContentLength
+	if env != nil && env.scheme == CryptoSchemeAESGCM {
+		// Leave out the authentication tag at the end of the object.
+		if size < 0 || opts.Range.Start >= size {
+			output.Body.Close()
+			return output, fmt.Errorf("range %s is not within the %d bytes of the object", opts.Range, size)
+		}
+		if n < 0 || start+n > size {
+			n = size - start
+		}
+		output.Size = size
+	}
+	var body io.Reader = output.Body
+	if n >= 0 {
+		body = io.LimitReader(body, n)
+		output.ContentLength = n - skip
+	}
+	if env != nil {
+		body = cipher.StreamReader{S: env.stream(start), R: body}
+	}
+	if _, err = io.CopyN(ioutil.Discard, body, skip); err != nil {
+		output.Body.Close()
+		return output, err
+	}
+	output.Body = readCloser{body, output.Body}
+	return output, nil
+}
+
+// openGCM replaces the body of a full read of a GCM object with the authenticated
+// content.
+func openGCM(env *envelope, output *GetObjectResponse) error {
+	sealed, err := ioutil.ReadAll(output.Body)
+	output.Body.Close()
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(env.block)
+	if err != nil {
+		return err
+	}
+	plain, err := aead.Open(nil, env.iv, sealed, nil)
+	if err != nil {
+		return fmt.Errorf("decrypt object: %v", err)
+	}
+	output.Body = ioutil.NopCloser(bytes.NewReader(plain))
+	output.ContentLength = int64(len(plain))
+	output.Size = int64(len(plain))
+	return nil
+}
@@ -0,0 +1,170 @@
+package bos
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func newTestEncryptionClient(t *testing.T, c *BosClient) *EncryptionClient {
+	wrapper, err := NewAESKeyWrapper(bytes.Repeat([]byte{3}, 32))
+	if err != nil {
+		t.Fatalf("NewAESKeyWrapper failed. %v", err)
+	}
+	return NewEncryptionClient(c, wrapper)
+}
+
+// checkEncryptedRanges reads content back through e in full and in several ranges.
+func checkEncryptedRanges(t *testing.T, e *EncryptionClient, objectName string, content []byte) {
+	res, err := e.GetObjectWithOptions(TestBukketName, objectName, nil)
+	if err != nil {
+		t.Fatalf("GetObjectWithOptions failed. %v", err)
+	}
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if !bytes.Equal(data, content) || res.Size != int64(len(content)) {
+		t.Errorf("GetObjectWithOptions of %s returned %d bytes, want %d", objectName, len(data), len(content))
+	}
+
+	size := int64(len(content))
+	for _, r := range []ObjectRange{
+		{0, 15}, {1, 1}, {17, 40}, {size - 5, -1}, {size - 20, size + 100}, {15, size - 1},
+	} {
+		rng := r
+		res, err := e.GetObjectWithOptions(TestBukketName, objectName, &GetObjectOptions{Range: &rng})
+		if err != nil {
+			t.Errorf("GetObjectWithOptions %s failed. %v", rng.String(), err)
+			continue
+		}
+		data, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		end := rng.End + 1
+		if rng.End < 0 || end > size {
+			end = size
+		}
+		if !bytes.Equal(data, content[rng.Start:end]) || res.ContentLength != int64(len(data)) || res.Size != size {
+			t.Errorf("GetObjectWithOptions %s of %s returned the wrong content", rng.String(), objectName)
+		}
+	}
+}
+
+func TestEncryptionClientGCM(t *testing.T) {
+	f, c := newFakeBos(t)
+	e := newTestEncryptionClient(t, c)
+	content := randomContent(t, 1000)
+
+	if _, err := e.PutObjectWithOptions(TestBukketName, "secret.bin", bytes.NewReader(content), nil); err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+	obj := f.object(TestBukketName, "secret.bin")
+	if len(obj.data) != len(content)+16 || bytes.Contains(obj.data, content[:64]) {
+		t.Errorf("PutObjectWithOptions stored %d bytes, want %d of ciphertext", len(obj.data), len(content)+16)
+	}
+	if obj.header.Get("x-bce-meta-"+cryptoMetaScheme) != CryptoSchemeAESGCM ||
+		obj.header.Get("x-bce-meta-"+cryptoMetaKey) == "" || obj.header.Get("x-bce-meta-"+cryptoMetaIV) == "" {
+		t.Errorf("PutObjectWithOptions did not store the envelope. %v", obj.header)
+	}
+	checkEncryptedRanges(t, e, "secret.bin", content)
+
+	if _, err := e.GetObjectWithOptions(TestBukketName, "secret.bin",
+		&GetObjectOptions{Range: &ObjectRange{Start: 1000, End: -1}}); err == nil {
+		t.Errorf("GetObjectWithOptions should refuse a range within the authentication tag")
+	}
+
+	f.mu.Lock()
+	obj.data[10] ^= 1
+	f.mu.Unlock()
+	if _, err := e.GetObjectWithOptions(TestBukketName, "secret.bin", nil); err == nil {
+		t.Errorf("GetObjectWithOptions should fail on tampered content")
+	}
+}
+
+func TestEncryptionClientCTR(t *testing.T) {
+	_, c := newFakeBos(t)
+	e := newTestEncryptionClient(t, c)
+	e.Scheme = CryptoSchemeAESCTR
+	content := randomContent(t, 777)
+
+	if _, err := e.PutObjectWithOptions(TestBukketName, "secret.bin", bytes.NewReader(content), nil); err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+	checkEncryptedRanges(t, e, "secret.bin", content)
+
+	res, err := e.GetObject(TestBukketName, "secret.bin", 100, 199)
+	if err != nil {
+		t.Fatalf("GetObject failed. %v", err)
+	}
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if !bytes.Equal(data, content[100:200]) {
+		t.Errorf("GetObject failed. Content Not Match.")
+	}
+}
+
+func TestEncryptionClientUpload(t *testing.T) {
+	f, c := newFakeBos(t)
+	e := newTestEncryptionClient(t, c)
+	e.Uploader.PartSize = MinPartSize
+	content := randomContent(t, 2*MinPartSize+1000)
+
+	res, err := e.Upload(TestBukketName, "master.mov", bytes.NewReader(content), nil)
+	if err != nil || res.UploadId == "" {
+		t.Fatalf("Upload failed. %v %+v", err, res)
+	}
+	obj := f.object(TestBukketName, "master.mov")
+	if obj.header.Get("Content-Type") != "video/quicktime" || bytes.Equal(obj.data[:64], content[:64]) {
+		t.Errorf("Upload stored plain content or lost the content type. %v", obj.header)
+	}
+	checkEncryptedRanges(t, e, "master.mov", content)
+
+	// A plain stream goes through the sequential path.
+	res, err = e.Upload(TestBukketName, "stream.bin", io.MultiReader(bytes.NewReader(content)), nil)
+	if err != nil || res.UploadId == "" {
+		t.Fatalf("Upload failed. %v %+v", err, res)
+	}
+	checkEncryptedRanges(t, e, "stream.bin", content)
+}
+
+func TestEncryptionClientKeys(t *testing.T) {
+	_, c := newFakeBos(t)
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey failed. %v", err)
+	}
+
+	writer := NewEncryptionClient(c, NewRSAKeyWrapper(&key.PublicKey, nil))
+	if _, err = writer.PutObjectWithOptions(TestBukketName, "rsa.bin", bytes.NewReader([]byte("sealed")), nil); err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+	if _, err = writer.GetObjectWithOptions(TestBukketName, "rsa.bin", nil); err == nil {
+		t.Errorf("GetObjectWithOptions should fail without the private key")
+	}
+	if _, err = newTestEncryptionClient(t, c).GetObjectWithOptions(TestBukketName, "rsa.bin", nil); err == nil {
+		t.Errorf("GetObjectWithOptions should fail with another key wrapping")
+	}
+
+	res, err := NewEncryptionClient(c, NewRSAKeyWrapper(nil, key)).GetObjectWithOptions(TestBukketName, "rsa.bin", nil)
+	if err != nil {
+		t.Fatalf("GetObjectWithOptions failed. %v", err)
+	}
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(data) != "sealed" {
+		t.Errorf("GetObjectWithOptions failed. Content Not Match.")
+	}
+
+	other, _ := NewAESKeyWrapper(bytes.Repeat([]byte{4}, 32))
+	e := newTestEncryptionClient(t, c)
+	e.PutObjectWithOptions(TestBukketName, "aes.bin", bytes.NewReader([]byte("sealed")), nil)
+	if _, err = NewEncryptionClient(c, other).GetObjectWithOptions(TestBukketName, "aes.bin", nil); err == nil {
+		t.Errorf("GetObjectWithOptions should fail with the wrong master key")
+	}
+
+	// Objects stored without client-side encryption are read as they are.
+	content := randomContent(t, 100)
+	c.PutObjectWithOptions(TestBukketName, "plain.bin", bytes.NewReader(content), nil)
+	checkEncryptedRanges(t, e, "plain.bin", content)
+}
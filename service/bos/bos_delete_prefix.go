@@ -0,0 +1,158 @@
+package bos
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const DefaultDeleteConcurrency = 4
+
+// DeletePrefixOptions tunes DeletePrefix. The zero value deletes MaxDeleteObjects keys
+// per request, DefaultDeleteConcurrency requests at a time.
+type DeletePrefixOptions struct {
+	// DryRun lists the objects that would be deleted, reporting them to Progress,
+	// without deleting anything.
+	DryRun bool
+
+	// Concurrency is the number of batch deletes in flight at the same time.
+	Concurrency int
+
+	// BatchSize is the number of keys sent in each batch delete, at most
+	// MaxDeleteObjects.
+	BatchSize int
+
+	// Progress, when set, is called after each batch. Calls are never concurrent.
+	Progress func(DeleteProgress)
+}
+
+// DeleteProgress describes the state of a DeletePrefix call after a batch.
+type DeleteProgress struct {
+	// Batch holds the keys of the batch just finished, or that would have been deleted
+	// in a dry run.
+	Batch []string
+
+	// Listed, Deleted and Failed are running totals.
+	Listed  int
+	Deleted int
+	Failed  int
+}
+
+type DeletePrefixResult struct {
+	Listed  int
+	Deleted int
+
+	// Errors lists the keys that could not be deleted.
+	Errors []DeleteObjectError
+}
+
+// DeletePrefix deletes every object whose key starts with prefix, such as all the
+// segments of a rendition set. Keys are listed a batch at a time and each batch is
+// deleted with DeleteMultipleObjects while the listing goes on. An empty prefix is
+// refused, as it would empty the bucket. Keys that fail to delete are reported in the
+// result; err is only set when listing or a whole batch fails.
+func (c *BosClient) DeletePrefix(bucketName, prefix string, opts *DeletePrefixOptions) (*DeletePrefixResult, error) {
+	if opts == nil {
+		opts = &DeletePrefixOptions{}
+	}
+	prefix = c.formatPath(prefix)
+	if prefix == "" {
+		return nil, errors.New("DeletePrefix needs a non-empty prefix")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 || batchSize > MaxDeleteObjects {
+		batchSize = MaxDeleteObjects
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDeleteConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result := &DeletePrefixResult{}
+	var mu sync.Mutex
+	var firstErr error
+	report := func(batch []string, errs []DeleteObjectError, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			cancel()
+			return
+		}
+		if !opts.DryRun {
+			result.Deleted += len(batch) - len(errs)
+			result.Errors = append(result.Errors, errs...)
+		}
+		if opts.Progress != nil {
+			opts.Progress(DeleteProgress{
+				Batch:   batch,
+				Listed:  result.Listed,
+				Deleted: result.Deleted,
+				Failed:  len(result.Errors),
+			})
+		}
+	}
+
+	var wg sync.WaitGroup
+	batches := make(chan []string)
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				if ctx.Err() != nil {
+					continue
+				}
+				res, err := c.DeleteMultipleObjects(bucketName, batch)
+				if err != nil {
+					report(batch, nil, err)
+					continue
+				}
+				report(batch, res.Errors, nil)
+			}
+		}()
+	}
+
+	var batch []string
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if opts.DryRun {
+			report(batch, nil, nil)
+		} else {
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+			}
+		}
+		batch = nil
+	}
+
+	it := c.NewObjectIterator(ctx, bucketName, &ListObjectsArgs{Prefix: prefix, MaxKeys: batchSize}, 0)
+	for it.Next() {
+		batch = append(batch, it.Object().ObjectName)
+		mu.Lock()
+		result.Listed++
+		mu.Unlock()
+		if len(batch) == batchSize {
+			flush()
+		}
+	}
+	flush()
+	close(batches)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package bos
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestDeletePrefix(t *testing.T) {
+	f, c := newFakeBos(t)
+	for i := 0; i < 23; i++ {
+		f.putObject(TestBukketName, fmt.Sprintf("out/720p/seg-%03d.ts", i), []byte("ts"), nil)
+	}
+	f.putObject(TestBukketName, "out/720p.m3u8", []byte("m3u8"), nil)
+
+	var listed []string
+	res, err := c.DeletePrefix(TestBukketName, "out/720p/", &DeletePrefixOptions{
+		DryRun:    true,
+		BatchSize: 5,
+		Progress:  func(p DeleteProgress) { listed = append(listed, p.Batch...) },
+	})
+	if err != nil || res.Listed != 23 || res.Deleted != 0 || len(listed) != 23 {
+		t.Fatalf("DeletePrefix dry run failed. %v %+v, %d reported", err, res, len(listed))
+	}
+	if f.object(TestBukketName, "out/720p/seg-000.ts") == nil {
+		t.Fatalf("DeletePrefix dry run deleted objects")
+	}
+
+	var last DeleteProgress
+	batches := 0
+	res, err = c.DeletePrefix(TestBukketName, "out/720p/", &DeletePrefixOptions{
+		BatchSize:   5,
+		Concurrency: 3,
+		Progress: func(p DeleteProgress) {
+			batches++
+			last = p
+		},
+	})
+	if err != nil || res.Listed != 23 || res.Deleted != 23 || len(res.Errors) != 0 {
+		t.Fatalf("DeletePrefix failed. %v %+v", err, res)
+	}
+	if batches != 5 || last.Deleted != 23 {
+		t.Errorf("DeletePrefix reported %d batches, last %+v", batches, last)
+	}
+	f.mu.Lock()
+	left := len(f.objects[TestBukketName])
+	f.mu.Unlock()
+	if left != 1 || f.object(TestBukketName, "out/720p.m3u8") == nil {
+		t.Errorf("DeletePrefix failed. %d objects left", left)
+	}
+
+	if _, err = c.DeletePrefix(TestBukketName, "/", nil); err == nil {
+		t.Errorf("DeletePrefix should refuse an empty prefix")
+	}
+}
+
+func TestDeletePrefixBatchFailure(t *testing.T) {
+	f, c := newFakeBos(t)
+	for i := 0; i < 20; i++ {
+		f.putObject(TestBukketName, fmt.Sprintf("tmp/%03d", i), []byte("x"), nil)
+	}
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodPost {
+			f.fail(w, http.StatusForbidden, "AccessDenied", "Access denied.")
+			return true
+		}
+		return false
+	}
+	if _, err := c.DeletePrefix(TestBukketName, "tmp/", &DeletePrefixOptions{BatchSize: 5}); err == nil {
+		t.Errorf("DeletePrefix should fail when a batch is rejected")
+	}
+}
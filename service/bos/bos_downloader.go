@@ -0,0 +1,383 @@
+package bos
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDownloadPartSize    = 8 * 1024 * 1024
+	DefaultDownloadConcurrency = 5
+)
+
+// DownloadSuffix is appended to the destination file name while a download is in
+// progress. The file only gets its real name once it is complete and verified.
+const DownloadSuffix = ".part"
+
+// Downloader fetches objects from BOS as a set of byte ranges downloaded concurrently.
+type Downloader struct {
+	Client *BosClient
+
+	// PartSize is the size of each ranged request.
+	PartSize int64
+
+	// Concurrency is the number of ranges fetched at the same time.
+	Concurrency int
+
+	// MaxRetries is how many times a failed range is retried before the download fails.
+	MaxRetries int
+}
+
+type DownloadResult struct {
+	BucketName string
+	ObjectName string
+	ETag       string
+	Size       int64
+}
+
+func NewDownloader(c *BosClient) *Downloader {
+	return &Downloader{
+		Client:      c,
+		PartSize:    DefaultDownloadPartSize,
+		Concurrency: DefaultDownloadConcurrency,
+		MaxRetries:  DefaultPartRetries,
+	}
+}
+
+// objectHead is what a download needs to know about an object before it starts.
+type objectHead struct {
+	size  int64
+	eTag  string
+	crc32 string
+}
+
+func (d *Downloader) head(bucketName, objectName string) (*objectHead, error) {
+	header, err := d.Client.headObject(bucketName, objectName, nil)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := parseObjectMeta(header)
+	if err != nil || meta.Size < 0 {
+		return nil, fmt.Errorf("bad Content-Length %q for %s/%s", header.Get("Content-Length"), bucketName, objectName)
+	}
+	return &objectHead{
+		size:  meta.Size,
+		eTag:  meta.ETag,
+		crc32: meta.ContentCRC32,
+	}, nil
+}
+
+func (d *Downloader) partSize() int64 {
+	if d.PartSize <= 0 {
+		return DefaultDownloadPartSize
+	}
+	return d.PartSize
+}
+
+// Download writes the object to w, which must accept writes at any offset and from
+// several goroutines at once, as an *os.File does. The object's ETag must stay the same
+// for the whole download. If w is also an io.ReaderAt and BOS knows the object's CRC32,
+// the written data is read back and checked against it.
+func (d *Downloader) Download(w io.WriterAt, bucketName, objectName string) (*DownloadResult, error) {
+	head, err := d.head(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	if err = d.download(context.Background(), w, bucketName, objectName, head, d.partSize(), nil, nil); err != nil {
+		return nil, err
+	}
+	if err = verifyDownload(w, head); err != nil {
+		return nil, err
+	}
+	return &DownloadResult{
+		BucketName: bucketName,
+		ObjectName: d.Client.formatPath(objectName),
+		ETag:       head.eTag,
+		Size:       head.size,
+	}, nil
+}
+
+// DownloadFile downloads the object into the named local file.
+func (d *Downloader) DownloadFile(bucketName, objectName, fileName string) (*DownloadResult, error) {
+	tmpName := fileName + DownloadSuffix
+	file, err := os.Create(tmpName)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := d.Download(file, bucketName, objectName)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return nil, err
+	}
+	if err = os.Rename(tmpName, fileName); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// downloadCheckpoint is the on-disk state of a resumable download.
+type downloadCheckpoint struct {
+	BucketName string `json:"bucket"`
+	ObjectName string `json:"key"`
+	ETag       string `json:"eTag"`
+	Size       int64  `json:"size"`
+	PartSize   int64  `json:"partSize"`
+
+	// Done lists the indexes of the ranges already written.
+	Done []int `json:"done"`
+}
+
+// ResumableDownloadFile downloads the object into the named local file like
+// DownloadFile, but records which ranges have been written in checkpointFile
+// (fileName+CheckpointSuffix when empty). Calling it again after an interruption
+// fetches only the missing ranges, unless the object has changed in the meantime.
+func (d *Downloader) ResumableDownloadFile(bucketName, objectName, fileName, checkpointFile string) (*DownloadResult, error) {
+	if checkpointFile == "" {
+		checkpointFile = fileName + CheckpointSuffix
+	}
+	objectName = d.Client.formatPath(objectName)
+	tmpName := fileName + DownloadSuffix
+
+	head, err := d.head(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &downloadCheckpoint{}
+	loaded, err := loadCheckpoint(checkpointFile, cp)
+	if err != nil {
+		return nil, err
+	}
+	if loaded && (cp.BucketName != bucketName || cp.ObjectName != objectName || cp.ETag != head.eTag ||
+		cp.Size != head.size || cp.PartSize <= 0) {
+		loaded = false
+	}
+	if loaded {
+		if info, err := os.Stat(tmpName); err != nil || info.Size() != head.size {
+			loaded = false
+		}
+	}
+
+	flags := os.O_RDWR | os.O_CREATE
+	if !loaded {
+		flags |= os.O_TRUNC
+		cp = &downloadCheckpoint{
+			BucketName: bucketName,
+			ObjectName: objectName,
+			ETag:       head.eTag,
+			Size:       head.size,
+			PartSize:   d.partSize(),
+		}
+	}
+	file, err := os.OpenFile(tmpName, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+	// Size the file up front so a resumed download can tell it apart from a stray one.
+	if err = file.Truncate(head.size); err != nil {
+		return nil, err
+	}
+	if err = saveCheckpoint(checkpointFile, cp); err != nil {
+		return nil, err
+	}
+
+	skip := map[int]bool{}
+	for _, i := range cp.Done {
+		skip[i] = true
+	}
+	var mu sync.Mutex
+	done := func(i int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		cp.Done = append(cp.Done, i)
+		return saveCheckpoint(checkpointFile, cp)
+	}
+
+	if err = d.download(context.Background(), file, bucketName, objectName, head, cp.PartSize, skip, done); err != nil {
+		return nil, err
+	}
+	if err = verifyDownload(file, head); err != nil {
+		os.Remove(checkpointFile)
+		return nil, err
+	}
+	err = file.Close()
+	file = nil
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmpName, fileName); err != nil {
+		return nil, err
+	}
+	os.Remove(checkpointFile)
+
+	return &DownloadResult{
+		BucketName: bucketName,
+		ObjectName: objectName,
+		ETag:       head.eTag,
+		Size:       head.size,
+	}, nil
+}
+
+// download fetches every range of the object not listed in skip into w, calling done,
+// when set, after each one.
+func (d *Downloader) download(ctx context.Context, w io.WriterAt, bucketName, objectName string,
+	head *objectHead, partSize int64, skip map[int]bool, done func(i int) error) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				start := int64(i) * partSize
+				end := start + partSize - 1
+				if end >= head.size {
+					end = head.size - 1
+				}
+				if err := d.fetchRange(ctx, w, bucketName, objectName, head, start, end); err != nil {
+					fail(fmt.Errorf("download range %d-%d: %v", start, end, err))
+					continue
+				}
+				if done != nil {
+					if err := done(i); err != nil {
+						fail(err)
+					}
+				}
+			}
+		}()
+	}
+
+	for i := 0; int64(i)*partSize < head.size; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		if !skip[i] {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// errObjectChanged stops a download whose object was replaced while it was running.
+type errObjectChanged struct {
+	want, got string
+}
+
+func (e *errObjectChanged) Error() string {
+	return fmt.Sprintf("object changed during download: eTag %q, expected %q", e.got, e.want)
+}
+
+// fetchRange writes bytes start through end of the object to w, retrying on failure.
+func (d *Downloader) fetchRange(ctx context.Context, w io.WriterAt, bucketName, objectName string,
+	head *objectHead, start, end int64) (err error) {
+
+	for attempt := 0; attempt <= d.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt*attempt) * 200 * time.Millisecond):
+			}
+		}
+
+		var res GetObjectResponse
+		res, err = d.Client.GetObjectWithOptions(bucketName, objectName,
+			&GetObjectOptions{Range: &ObjectRange{Start: start, End: end}})
+		if err != nil {
+			continue
+		}
+		if head.eTag != "" && res.ETag != "" && res.ETag != head.eTag {
+			res.Body.Close()
+			return &errObjectChanged{want: head.eTag, got: res.ETag}
+		}
+
+		var n int64
+		n, err = io.Copy(&offsetWriter{w: w, offset: start}, io.LimitReader(res.Body, end-start+1))
+		res.Body.Close()
+		if err == nil && n != end-start+1 {
+			err = fmt.Errorf("got %d bytes, expected %d", n, end-start+1)
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// offsetWriter turns sequential writes into writes at increasing offsets of w.
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, err := o.w.WriteAt(p, o.offset)
+	o.offset += int64(n)
+	return n, err
+}
+
+// verifyDownload checks the downloaded data against the CRC32 BOS holds for the object,
+// when there is one and the data can be read back.
+func verifyDownload(w io.WriterAt, head *objectHead) error {
+	r, ok := w.(io.ReaderAt)
+	if !ok || head.crc32 == "" {
+		return nil
+	}
+	want, err := strconv.ParseUint(head.crc32, 10, 32)
+	if err != nil {
+		return nil
+	}
+
+	h := crc32.NewIEEE()
+	if _, err = io.Copy(h, io.NewSectionReader(r, 0, head.size)); err != nil {
+		return err
+	}
+	if got := h.Sum32(); got != uint32(want) {
+		return fmt.Errorf("downloaded data has CRC32 %d, expected %d", got, want)
+	}
+	return nil
+}
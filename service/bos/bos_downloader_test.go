@@ -0,0 +1,175 @@
+package bos
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// rangeGets counts the ranged GET requests f has seen so far, by Range header.
+func rangeGets(f *fakeBos) map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	counts := map[string]int{}
+	for _, r := range f.requests {
+		if r.Method == http.MethodGet && r.Header.Get("Range") != "" {
+			counts[r.Header.Get("Range")]++
+		}
+	}
+	return counts
+}
+
+func TestGetObjectWithOptionsRange(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "range", []byte("0123456789"), nil)
+
+	for _, tc := range []struct {
+		rng  ObjectRange
+		want string
+	}{
+		{ObjectRange{Start: 0, End: 0}, "0"},
+		{ObjectRange{Start: 3, End: 5}, "345"},
+		{ObjectRange{Start: 7, End: -1}, "789"},
+	} {
+		res, err := c.GetObjectWithOptions(TestBukketName, "range", &GetObjectOptions{Range: &tc.rng})
+		if err != nil {
+			t.Fatalf("GetObjectWithOptions failed. %v", err)
+		}
+		got, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(got) != tc.want {
+			t.Errorf("GetObjectWithOptions %s = %q, want %q", tc.rng.String(), got, tc.want)
+		}
+	}
+
+	if _, err := c.GetObjectWithOptions(TestBukketName, "range",
+		&GetObjectOptions{Range: &ObjectRange{Start: 5, End: 4}}); err == nil {
+		t.Errorf("GetObjectWithOptions should reject an inverted range")
+	}
+}
+
+func TestDownloaderDownloadFile(t *testing.T) {
+	f, c := newFakeBos(t)
+	content := randomContent(t, 10500)
+	obj := f.putObject(TestBukketName, "video.mp4", content, nil)
+	obj.header.Set("X-Bce-Content-Crc32", fmt.Sprintf("%d", crc32.ChecksumIEEE(content)))
+
+	d := NewDownloader(c)
+	d.PartSize = 1000
+	d.Concurrency = 4
+
+	fileName := filepath.Join(t.TempDir(), "video.mp4")
+	res, err := d.DownloadFile(TestBukketName, "video.mp4", fileName)
+	if err != nil {
+		t.Fatalf("DownloadFile failed. %v", err)
+	}
+	if res.Size != int64(len(content)) || res.ETag != obj.eTag {
+		t.Errorf("DownloadFile failed. result = %+v", res)
+	}
+	got, err := ioutil.ReadFile(fileName)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("DownloadFile failed. Content Not Match.")
+	}
+	if n := len(rangeGets(f)); n != 11 {
+		t.Errorf("DownloadFile failed. %d ranges fetched, want 11", n)
+	}
+
+	obj.header.Set("X-Bce-Content-Crc32", "1")
+	if _, err = d.DownloadFile(TestBukketName, "video.mp4", fileName+".bad"); err == nil {
+		t.Errorf("DownloadFile should fail on a CRC32 mismatch")
+	}
+	if _, err = os.Stat(fileName + ".bad" + DownloadSuffix); !os.IsNotExist(err) {
+		t.Errorf("DownloadFile failed. partial file left behind")
+	}
+}
+
+func TestDownloaderResume(t *testing.T) {
+	f, c := newFakeBos(t)
+	content := randomContent(t, 5000)
+	f.putObject(TestBukketName, "live.ts", content, nil)
+
+	d := NewDownloader(c)
+	d.PartSize = 1000
+	d.Concurrency = 1
+	d.MaxRetries = 0
+
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Range") == "bytes=3000-3999" {
+			f.fail(w, http.StatusInternalServerError, "InternalError", "link down")
+			return true
+		}
+		return false
+	}
+	fileName := filepath.Join(t.TempDir(), "live.ts")
+	if _, err := d.ResumableDownloadFile(TestBukketName, "live.ts", fileName, ""); err == nil {
+		t.Fatalf("ResumableDownloadFile should fail.")
+	}
+	cp := &downloadCheckpoint{}
+	if ok, _ := loadCheckpoint(fileName+CheckpointSuffix, cp); !ok || len(cp.Done) != 3 {
+		t.Fatalf("ResumableDownloadFile failed. checkpoint = %+v", cp)
+	}
+
+	f.intercept = nil
+	before := rangeGets(f)
+	if _, err := d.ResumableDownloadFile(TestBukketName, "live.ts", fileName, ""); err != nil {
+		t.Fatalf("ResumableDownloadFile failed. %v", err)
+	}
+	after := rangeGets(f)
+	for rng, n := range after {
+		fetched := n - before[rng]
+		switch rng {
+		case "bytes=3000-3999", "bytes=4000-4999":
+			if fetched != 1 {
+				t.Errorf("ResumableDownloadFile failed. %s fetched %d times", rng, fetched)
+			}
+		default:
+			if fetched != 0 {
+				t.Errorf("ResumableDownloadFile failed. %s fetched again", rng)
+			}
+		}
+	}
+	got, err := ioutil.ReadFile(fileName)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("ResumableDownloadFile failed. Content Not Match.")
+	}
+	if _, err = os.Stat(fileName + CheckpointSuffix); !os.IsNotExist(err) {
+		t.Errorf("ResumableDownloadFile failed. checkpoint not removed")
+	}
+}
+
+func TestDownloaderObjectChanged(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "changing", randomContent(t, 3000), nil)
+
+	d := NewDownloader(c)
+	d.PartSize = 1000
+	d.Concurrency = 1
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Range") == "bytes=1000-1999" {
+			f.putObject(TestBukketName, "changing", randomContent(t, 3000), nil)
+		}
+		return false
+	}
+
+	var buf writerAtBuffer
+	if _, err := d.Download(&buf, TestBukketName, "changing"); err == nil {
+		t.Errorf("Download should fail when the object changes")
+	}
+}
+
+// writerAtBuffer is an in-memory io.WriterAt.
+type writerAtBuffer struct {
+	data []byte
+}
+
+func (b *writerAtBuffer) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(b.data) {
+		b.data = append(b.data, make([]byte, end-len(b.data))...)
+	}
+	return copy(b.data[off:], p), nil
+}
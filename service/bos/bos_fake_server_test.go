@@ -0,0 +1,1112 @@
+package bos
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+)
+
+// fakeObject is an object stored by fakeBos.
+type fakeObject struct {
+	data         []byte
+	header       http.Header
+	eTag         string
+	lastModified time.Time
+}
+
+// fakeUpload is a multipart upload in progress on fakeBos.
+type fakeUpload struct {
+	bucketName string
+	objectName string
+	header     http.Header
+	initiated  time.Time
+	parts      map[int]*fakeObject
+}
+
+// fakeBos is an in-memory stand-in for the BOS HTTP API, just faithful enough to
+// exercise the client. Buckets spring into existence on first use.
+type fakeBos struct {
+	mu      sync.Mutex
+	server  *httptest.Server
+	objects map[string]map[string]*fakeObject
+	uploads map[string]*fakeUpload
+	nextId  int
+
+	// configs holds the JSON documents of sub-resources such as ?acl, keyed by
+	// "<bucket>[/<object>]?<sub-resource>".
+	configs map[string][]byte
+
+	// intercept, when set, sees every request first and may answer it itself by
+	// returning true. Tests use it to inject failures.
+	intercept func(w http.ResponseWriter, r *http.Request) bool
+
+	// omitNextMarker leaves nextMarker out of object listings, as BOS does when no
+	// delimiter is given, so that clients must continue from the last entry.
+	omitNextMarker bool
+
+	// requests records the method, path, query and headers of every request served.
+	requests []*http.Request
+}
+
+// The credentials of the clients talking to fakeBos. Only form uploads check them.
+const (
+	fakeAccessKey = "ak"
+	fakeSecretKey = "sk"
+)
+
+func newFakeBos(t *testing.T) (*fakeBos, *BosClient) {
+	f := &fakeBos{
+		objects: map[string]map[string]*fakeObject{},
+		uploads: map[string]*fakeUpload{},
+		configs: map[string][]byte{},
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.server.Close)
+
+	c, err := NewBosClient(auth.NewBceCredentials(fakeAccessKey, fakeSecretKey))
+	if err != nil {
+		t.Fatalf("NewBosClient failed. %v", err)
+	}
+	c.Host = strings.TrimPrefix(f.server.URL, "http://")
+	return f, c
+}
+
+// object returns the stored object, or nil.
+func (f *fakeBos) object(bucketName, objectName string) *fakeObject {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[bucketName][objectName]
+}
+
+// putObject stores data directly, bypassing the HTTP API.
+func (f *fakeBos) putObject(bucketName, objectName string, data []byte, header http.Header) *fakeObject {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if header == nil {
+		header = http.Header{}
+	}
+	obj := &fakeObject{
+		data:         data,
+		header:       header,
+		eTag:         fmt.Sprintf("%x", md5.Sum(data)),
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+	if f.objects[bucketName] == nil {
+		f.objects[bucketName] = map[string]*fakeObject{}
+	}
+	f.objects[bucketName][objectName] = obj
+	return obj
+}
+
+// lastRequest returns the most recent request whose method matches.
+func (f *fakeBos) lastRequest(method string) *http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.requests) - 1; i >= 0; i-- {
+		if f.requests[i].Method == method {
+			return f.requests[i]
+		}
+	}
+	return nil
+}
+
+func (f *fakeBos) fail(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"code":      code,
+		"message":   message,
+		"requestId": "fake-request-id",
+	})
+}
+
+func (f *fakeBos) reply(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeBos) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, &http.Request{
+		Method:        r.Method,
+		URL:           r.URL,
+		Header:        r.Header,
+		ContentLength: r.ContentLength,
+	})
+	intercept := f.intercept
+	f.mu.Unlock()
+
+	if intercept != nil && intercept(w, r) {
+		return
+	}
+
+	// Paths look like /v1/<bucket>[/<object>].
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		f.fail(w, http.StatusNotImplemented, "NotImplemented", "service operations are not faked")
+		return
+	}
+	bucketName := parts[1]
+	if len(parts) == 2 || parts[2] == "" {
+		f.serveBucket(w, r, bucketName, body)
+		return
+	}
+	f.serveObject(w, r, bucketName, parts[2], body)
+}
+
+// fakeBucketConfigs maps the bucket sub-resources that fakeBos stores verbatim to the
+// error code BOS gives when one is not set.
+var fakeBucketConfigs = map[string]string{
+	"lifecycle": "NoLifecycleConfiguration",
+	"cors":      "NoCORSConfiguration",
+	"website":   "NoSuchWebsiteConfiguration",
+}
+
+func (f *fakeBos) serveBucket(w http.ResponseWriter, r *http.Request, bucketName string, body []byte) {
+	query := r.URL.Query()
+	for resource, missing := range fakeBucketConfigs {
+		if _, ok := query[resource]; ok {
+			f.serveConfig(w, r, bucketName+"?"+resource, body, missing)
+			return
+		}
+	}
+	switch {
+	case len(query["acl"]) > 0:
+		f.serveAcl(w, r, bucketName, body)
+	case len(query["logging"]) > 0:
+		f.serveLogging(w, r, bucketName, body)
+	case len(query["replication"]) > 0:
+		f.serveConfig(w, r, bucketName+"?replication&id="+query.Get("id"), body, "NoReplicationConfiguration")
+	case r.Method == http.MethodGet && len(query["fetch"]) > 0:
+		f.serveConfig(w, r, bucketName+"?fetch&jobId="+query.Get("jobId"), nil, "NoSuchFetchJob")
+	case r.Method == http.MethodGet && len(query["replicationProgress"]) > 0:
+		f.replicationProgress(w, bucketName, query.Get("id"))
+	case r.Method == http.MethodGet && len(query["uploads"]) > 0:
+		f.listUploads(w, r, bucketName)
+	case r.Method == http.MethodGet && isListQuery(query):
+		f.listObjects(w, r, bucketName)
+	case r.Method == http.MethodPost && len(query["delete"]) > 0:
+		f.deleteObjects(w, bucketName, body)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data"):
+		f.postObject(w, r, bucketName, body)
+	default:
+		f.fail(w, http.StatusNotImplemented, "NotImplemented", "bucket operation is not faked")
+	}
+}
+
+func (f *fakeBos) deleteObjects(w http.ResponseWriter, bucketName string, body []byte) {
+	var req struct {
+		Objects []struct {
+			Key string `json:"key"`
+		} `json:"objects"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || len(req.Objects) == 0 ||
+		len(req.Objects) > MaxDeleteObjects {
+
+		f.fail(w, http.StatusBadRequest, "MalformedJSON", "bad object list")
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, obj := range req.Objects {
+		delete(f.objects[bucketName], obj.Key)
+	}
+}
+
+// serveConfig stores, returns or deletes the JSON document of a sub-resource. A
+// document that was never set is reported with the given error code.
+func (f *fakeBos) serveConfig(w http.ResponseWriter, r *http.Request, key string, body []byte, missing string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		if !json.Valid(body) {
+			f.fail(w, http.StatusBadRequest, "MalformedJSON", "body is not JSON")
+			return
+		}
+		f.configs[key] = body
+	case http.MethodGet:
+		data, ok := f.configs[key]
+		if !ok {
+			f.fail(w, http.StatusNotFound, missing, key+" is not set")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.configs, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// serveAcl serves ?acl for the bucket or object named by resource. A canned ACL is
+// stored as the grants it stands for.
+func (f *fakeBos) serveAcl(w http.ResponseWriter, r *http.Request, resource string, body []byte) {
+	if canned := r.Header.Get(auth.BCE_ACL); canned != "" && r.Method == http.MethodPut {
+		grants := []GranteeGroup{{Grantee: []GranteeInfo{{Id: "owner-id"}}, Permission: []string{PermissionFullControl}}}
+		switch canned {
+		case CannedAclPrivate:
+		case CannedAclPublicRead:
+			grants = append(grants, GranteeGroup{Grantee: []GranteeInfo{{Id: GranteeAllUsers}}, Permission: []string{PermissionRead}})
+		case CannedAclPublicReadWrite:
+			grants = append(grants, GranteeGroup{Grantee: []GranteeInfo{{Id: GranteeAllUsers}},
+				Permission: []string{PermissionRead, PermissionWrite}})
+		default:
+			f.fail(w, http.StatusBadRequest, "InvalidArgument", "unknown canned ACL "+canned)
+			return
+		}
+		body, _ = json.Marshal(map[string][]GranteeGroup{"accessControlList": grants})
+	}
+	f.serveConfig(w, r, resource+"?acl", body, "NoSuchAcl")
+}
+
+// serveLogging serves ?logging, which unlike other sub-resources reads as disabled
+// rather than missing when it was never set.
+func (f *fakeBos) serveLogging(w http.ResponseWriter, r *http.Request, bucketName string, body []byte) {
+	if r.Method == http.MethodPut {
+		var logging BucketLogging
+		if err := json.Unmarshal(body, &logging); err != nil || logging.TargetBucket == "" {
+			f.fail(w, http.StatusBadRequest, "MalformedJSON", "bad logging configuration")
+			return
+		}
+		logging.Status = LoggingStatusEnabled
+		body, _ = json.Marshal(logging)
+	}
+	if r.Method == http.MethodGet {
+		f.mu.Lock()
+		_, ok := f.configs[bucketName+"?logging"]
+		f.mu.Unlock()
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"status":"disabled"}`))
+			return
+		}
+	}
+	f.serveConfig(w, r, bucketName+"?logging", body, "")
+}
+
+// replicationProgress reports a stored replication rule as fully caught up.
+func (f *fakeBos) replicationProgress(w http.ResponseWriter, bucketName, ruleId string) {
+	f.mu.Lock()
+	data, ok := f.configs[bucketName+"?replication&id="+ruleId]
+	f.mu.Unlock()
+	var rule ReplicationRule
+	if !ok || json.Unmarshal(data, &rule) != nil {
+		f.fail(w, http.StatusNotFound, "NoReplicationConfiguration", "no replication rule "+ruleId)
+		return
+	}
+	progress := BucketReplicationProgress{Status: rule.Status, LatestReplicationTime: time.Now().UTC().Truncate(time.Second)}
+	if rule.ReplicateHistory != nil {
+		progress.HistoryReplicationPercent = 100
+	}
+	f.reply(w, progress)
+}
+
+// isListQuery reports whether a bucket query carries only ListObjects parameters
+// rather than naming a sub-resource such as ?acl.
+func isListQuery(query url.Values) bool {
+	for k := range query {
+		switch k {
+		case "prefix", "marker", "maxKeys", "delimiter":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// listKeys walks the sorted keys after marker that start with prefix, rolling keys up
+// to the next delimiter into common prefixes, and stops after maxKeys entries.
+func listKeys(keys []string, prefix, delimiter, marker string, maxKeys int) (
+	contents, prefixes []string, truncated bool, next string) {
+
+	sort.Strings(keys)
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if k <= marker || !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		entry, isPrefix := k, false
+		if delimiter != "" {
+			if i := strings.Index(k[len(prefix):], delimiter); i >= 0 {
+				entry, isPrefix = k[:len(prefix)+i+len(delimiter)], true
+				if entry <= marker || seen[entry] {
+					continue
+				}
+			}
+		}
+		if len(contents)+len(prefixes) == maxKeys {
+			return contents, prefixes, true, next
+		}
+		if isPrefix {
+			seen[entry] = true
+			prefixes = append(prefixes, entry)
+		} else {
+			contents = append(contents, entry)
+		}
+		next = entry
+	}
+	return contents, prefixes, false, next
+}
+
+func (f *fakeBos) listObjects(w http.ResponseWriter, r *http.Request, bucketName string) {
+	query := r.URL.Query()
+	maxKeys := 1000
+	if query.Get("maxKeys") != "" {
+		maxKeys, _ = strconv.Atoi(query.Get("maxKeys"))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for k := range f.objects[bucketName] {
+		keys = append(keys, k)
+	}
+	contents, prefixes, truncated, next := listKeys(keys, query.Get("prefix"), query.Get("delimiter"),
+		query.Get("marker"), maxKeys)
+
+	res := map[string]interface{}{
+		"name":        bucketName,
+		"prefix":      query.Get("prefix"),
+		"delimiter":   query.Get("delimiter"),
+		"marker":      query.Get("marker"),
+		"maxKeys":     maxKeys,
+		"isTruncated": truncated,
+	}
+	if truncated && !f.omitNextMarker {
+		res["nextMarker"] = next
+	}
+	var objects []map[string]interface{}
+	for _, k := range contents {
+		obj := f.objects[bucketName][k]
+		storageClass := obj.header.Get(auth.BCE_STORAGE_CLASS)
+		if storageClass == "" {
+			storageClass = StorageClassStandard
+		}
+		objects = append(objects, map[string]interface{}{
+			"key":          k,
+			"lastModified": obj.lastModified.Format(time.RFC3339),
+			"eTag":         obj.eTag,
+			"size":         len(obj.data),
+			"storageClass": storageClass,
+			"owner":        map[string]string{"id": "owner-id", "displayName": "owner"},
+		})
+	}
+	res["contents"] = objects
+	var common []map[string]string
+	for _, p := range prefixes {
+		common = append(common, map[string]string{"prefix": p})
+	}
+	res["commonPrefixes"] = common
+	f.reply(w, res)
+}
+
+func (f *fakeBos) listUploads(w http.ResponseWriter, r *http.Request, bucketName string) {
+	query := r.URL.Query()
+	maxUploads := 1000
+	if query.Get("maxUploads") != "" {
+		maxUploads, _ = strconv.Atoi(query.Get("maxUploads"))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	byKey := map[string][]string{}
+	var keys []string
+	for id, u := range f.uploads {
+		if u.bucketName == bucketName {
+			if byKey[u.objectName] == nil {
+				keys = append(keys, u.objectName)
+			}
+			byKey[u.objectName] = append(byKey[u.objectName], id)
+		}
+	}
+	contents, prefixes, truncated, next := listKeys(keys, query.Get("prefix"), query.Get("delimiter"),
+		query.Get("keyMarker"), maxUploads)
+
+	res := map[string]interface{}{
+		"bucket":      bucketName,
+		"prefix":      query.Get("prefix"),
+		"keyMarker":   query.Get("keyMarker"),
+		"maxUploads":  maxUploads,
+		"isTruncated": truncated,
+	}
+	if truncated {
+		res["nextKeyMarker"] = next
+	}
+	var uploads []map[string]interface{}
+	for _, k := range contents {
+		for _, id := range byKey[k] {
+			uploads = append(uploads, map[string]interface{}{
+				"key":       k,
+				"uploadId":  id,
+				"initiated": f.uploads[id].initiated.Format(time.RFC3339),
+				"owner":     map[string]string{"id": "owner-id", "displayName": "owner"},
+			})
+		}
+	}
+	res["uploads"] = uploads
+	var common []map[string]string
+	for _, p := range prefixes {
+		common = append(common, map[string]string{"prefix": p})
+	}
+	res["commonPrefixes"] = common
+	f.reply(w, res)
+}
+
+// objectHeader picks the headers of r that are stored with an object.
+func objectHeader(r *http.Request) http.Header {
+	header := http.Header{}
+	for k, v := range r.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, auth.BCE_PREFIX) || strings.HasPrefix(lk, "content-") ||
+			lk == "cache-control" || lk == "expires" {
+			header[k] = v
+		}
+	}
+	header.Del("Content-Length")
+	header.Del("Content-Md5")
+	header.Del(auth.BCE_DATE)
+	// Like BOS, keep only the fingerprint of a customer-provided key.
+	header.Del(auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY)
+	for k := range header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, auth.BCE_COPY_SOURCE) || lk == auth.BCE_COPY_METADATA_DIRECTIVE {
+			header.Del(k)
+		}
+	}
+	return header
+}
+
+// customerKeyMatches reports whether r carries, in keyHeader, the customer key whose
+// MD5 an object was encrypted with, if it was.
+func customerKeyMatches(stored http.Header, r *http.Request, keyHeader string) bool {
+	want := stored.Get(auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY_MD5)
+	if want == "" {
+		return true
+	}
+	key, err := base64.StdEncoding.DecodeString(r.Header.Get(keyHeader))
+	if err != nil {
+		return false
+	}
+	sum := md5.Sum(key)
+	return base64.StdEncoding.EncodeToString(sum[:]) == want
+}
+
+// copySource resolves the x-bce-copy-source of r, checks its copy conditions and returns
+// the bytes to copy, honouring x-bce-copy-source-range. On failure it answers r itself.
+// f.mu must be held.
+func (f *fakeBos) copySource(w http.ResponseWriter, r *http.Request) (*fakeObject, []byte, bool) {
+	source, err := url.PathUnescape(r.Header.Get(auth.BCE_COPY_SOURCE))
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "InvalidArgument", "bad copy source")
+		return nil, nil, false
+	}
+	path := strings.SplitN(strings.TrimPrefix(source, "/"), "/", 2)
+	if len(path) != 2 || f.objects[path[0]][path[1]] == nil {
+		f.fail(w, http.StatusNotFound, "NoSuchKey", "copy source does not exist")
+		return nil, nil, false
+	}
+	src := f.objects[path[0]][path[1]]
+	if !customerKeyMatches(src.header, r, auth.BCE_COPY_SOURCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY) {
+		f.fail(w, http.StatusForbidden, "AccessDenied", "copy source customer key does not match")
+		return nil, nil, false
+	}
+
+	if evalConditions(src, r.Header.Get(auth.BCE_COPY_SOURCE_IF_MATCH), r.Header.Get(auth.BCE_COPY_SOURCE_IF_NONE_MATCH),
+		r.Header.Get(auth.BCE_COPY_SOURCE_IF_MODIFIED_SINCE), r.Header.Get(auth.BCE_COPY_SOURCE_IF_UNMODIFIED_SINCE)) != 0 {
+		f.fail(w, http.StatusPreconditionFailed, "PreconditionFailed", "copy source condition failed")
+		return nil, nil, false
+	}
+
+	data := src.data
+	if rng := r.Header.Get(auth.BCE_COPY_SOURCE_RANGE); rng != "" {
+		start, end, ok := parseFakeRange(rng, int64(len(src.data)))
+		if !ok {
+			f.fail(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", rng)
+			return nil, nil, false
+		}
+		data = src.data[start : end+1]
+	}
+	return src, data, true
+}
+
+// copyObject serves a single-request CopyObject.
+func (f *fakeBos) copyObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string) {
+	f.mu.Lock()
+	src, data, ok := f.copySource(w, r)
+	f.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	header := objectHeader(r)
+	if r.Header.Get(auth.BCE_COPY_METADATA_DIRECTIVE) != MetadataDirectiveReplace {
+		requested := header
+		header = http.Header{}
+		for k, v := range src.header {
+			if lk := strings.ToLower(k); !strings.HasPrefix(lk, auth.BCE_SERVER_SIDE_ENCRYPTION) && lk != auth.BCE_RESTORE {
+				header[k] = v
+			}
+		}
+		// The copy is encrypted as the request asks, not as the source was.
+		for k, v := range requested {
+			lk := strings.ToLower(k)
+			if lk == auth.BCE_STORAGE_CLASS || lk == auth.BCE_ACL || strings.HasPrefix(lk, auth.BCE_SERVER_SIDE_ENCRYPTION) {
+				header[k] = v
+			}
+		}
+	}
+	obj := f.putObject(bucketName, objectName, append([]byte(nil), data...), header)
+	f.reply(w, CopyObjectResponse{LastModified: obj.lastModified.Format(time.RFC3339), ETag: obj.eTag})
+}
+
+func (f *fakeBos) serveObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string, body []byte) {
+	query := r.URL.Query()
+	if _, ok := query["uploads"]; ok || query.Get("uploadId") != "" {
+		f.serveMultipart(w, r, bucketName, objectName, body)
+		return
+	}
+	if _, ok := query["acl"]; ok {
+		if f.object(bucketName, objectName) == nil {
+			f.fail(w, http.StatusNotFound, "NoSuchKey", "object does not exist")
+			return
+		}
+		f.serveAcl(w, r, bucketName+"/"+objectName, body)
+		return
+	}
+	if _, ok := query["tagging"]; ok {
+		if f.object(bucketName, objectName) == nil {
+			f.fail(w, http.StatusNotFound, "NoSuchKey", "object does not exist")
+			return
+		}
+		f.serveConfig(w, r, bucketName+"/"+objectName+"?tagging", body, "NoSuchTagSet")
+		return
+	}
+	if _, ok := query["append"]; ok && r.Method == http.MethodPost {
+		f.appendObject(w, r, bucketName, objectName, body)
+		return
+	}
+	if _, ok := query["restore"]; ok && r.Method == http.MethodPost {
+		f.restoreObject(w, r, bucketName, objectName)
+		return
+	}
+	if _, ok := query["fetch"]; ok && r.Method == http.MethodPost {
+		f.fetchObject(w, r, bucketName, objectName)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get(auth.BCE_COPY_SOURCE) != "" {
+			f.copyObject(w, r, bucketName, objectName)
+			return
+		}
+		obj := f.putObject(bucketName, objectName, body, objectHeader(r))
+		w.Header().Set("ETag", "\""+obj.eTag+"\"")
+	case http.MethodGet, http.MethodHead:
+		obj := f.object(bucketName, objectName)
+		if obj == nil {
+			f.fail(w, http.StatusNotFound, "NoSuchKey", "object does not exist")
+			return
+		}
+		if !customerKeyMatches(obj.header, r, auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY) {
+			f.fail(w, http.StatusForbidden, "AccessDenied", "customer key does not match")
+			return
+		}
+		switch evalConditions(obj, r.Header.Get("If-Match"), r.Header.Get("If-None-Match"),
+			r.Header.Get("If-Modified-Since"), r.Header.Get("If-Unmodified-Since")) {
+		case http.StatusNotModified:
+			w.Header().Set("ETag", "\""+obj.eTag+"\"")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case http.StatusPreconditionFailed:
+			f.fail(w, http.StatusPreconditionFailed, "PreconditionFailed", "object condition failed")
+			return
+		}
+		if r.Method == http.MethodGet && obj.header.Get(auth.BCE_STORAGE_CLASS) == StorageClassArchive &&
+			!strings.Contains(obj.header.Get(auth.BCE_RESTORE), `ongoing-request="false"`) {
+			f.fail(w, http.StatusForbidden, "InvalidObjectState", "object is archived")
+			return
+		}
+		for k, v := range obj.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", "\""+obj.eTag+"\"")
+		w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+		if obj.header.Get(auth.BCE_OBJECT_TYPE) == ObjectTypeAppendable {
+			w.Header().Set(auth.BCE_NEXT_APPEND_OFFSET, strconv.Itoa(len(obj.data)))
+		}
+		data, status := obj.data, http.StatusOK
+		if rng := r.Header.Get("Range"); rng != "" && r.Method == http.MethodGet {
+			start, end, ok := parseFakeRange(rng, int64(len(obj.data)))
+			if !ok {
+				f.fail(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", rng)
+				return
+			}
+			data, status = obj.data[start:end+1], http.StatusPartialContent
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(obj.data)))
+		}
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		f.mu.Lock()
+		delete(f.objects[bucketName], objectName)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// appendObject serves AppendObject. Each append replaces the stored object, so readers
+// holding the previous one are not disturbed.
+func (f *fakeBos) appendObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string, body []byte) {
+	offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	sum := md5.Sum(body)
+	if m := r.Header.Get("Content-Md5"); m != "" && m != base64.StdEncoding.EncodeToString(sum[:]) {
+		f.fail(w, http.StatusBadRequest, "BadDigest", "Content-MD5 does not match the body")
+		return
+	}
+	if c := r.Header.Get(auth.BCE_CONTENT_CRC32); c != "" && c != strconv.FormatUint(uint64(crc32.ChecksumIEEE(body)), 10) {
+		f.fail(w, http.StatusBadRequest, "BadDigest", "CRC32 does not match the body")
+		return
+	}
+
+	f.mu.Lock()
+	old := f.objects[bucketName][objectName]
+	header, data := objectHeader(r), []byte(nil)
+	if old != nil {
+		if old.header.Get(auth.BCE_OBJECT_TYPE) != ObjectTypeAppendable {
+			f.mu.Unlock()
+			f.fail(w, http.StatusConflict, "ObjectUnappendable", "object is not appendable")
+			return
+		}
+		header, data = old.header, old.data
+	}
+	if offset != int64(len(data)) {
+		f.mu.Unlock()
+		f.fail(w, http.StatusConflict, "OffsetIncorrect", fmt.Sprintf("object is %d bytes", len(data)))
+		return
+	}
+	header.Set(auth.BCE_OBJECT_TYPE, ObjectTypeAppendable)
+	data = append(append([]byte{}, data...), body...)
+	obj := &fakeObject{
+		data:         data,
+		header:       header,
+		eTag:         fmt.Sprintf("%x", md5.Sum(data)),
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+	if f.objects[bucketName] == nil {
+		f.objects[bucketName] = map[string]*fakeObject{}
+	}
+	f.objects[bucketName][objectName] = obj
+	f.mu.Unlock()
+
+	w.Header().Set("ETag", "\""+obj.eTag+"\"")
+	w.Header().Set(auth.BCE_NEXT_APPEND_OFFSET, strconv.Itoa(len(data)))
+	w.Header().Set(auth.BCE_CONTENT_CRC32, strconv.FormatUint(uint64(crc32.ChecksumIEEE(data)), 10))
+}
+
+// fetchObject serves FetchObject. An async fetch downloads the source in the background
+// after answering, and its job is served by GetFetchJob.
+func (f *fakeBos) fetchObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string) {
+	source, mode := r.Header.Get(auth.BCE_FETCH_SOURCE), r.Header.Get(auth.BCE_FETCH_MODE)
+	if source == "" || (mode != FetchModeSync && mode != FetchModeAsync) {
+		f.fail(w, http.StatusBadRequest, "InvalidArgument", "bad fetch source or mode")
+		return
+	}
+	header := http.Header{}
+	if class := r.Header.Get(auth.BCE_STORAGE_CLASS); class != "" {
+		header.Set(auth.BCE_STORAGE_CLASS, class)
+	}
+	download := func() error {
+		res, err := http.Get(source)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		data, err := ioutil.ReadAll(res.Body)
+		if err != nil || res.StatusCode != http.StatusOK {
+			return fmt.Errorf("fetching %s: %s %v", source, res.Status, err)
+		}
+		header.Set("Content-Type", res.Header.Get("Content-Type"))
+		f.putObject(bucketName, objectName, data, header)
+		return nil
+	}
+
+	if mode == FetchModeAsync {
+		f.mu.Lock()
+		f.nextId++
+		job := FetchJob{JobId: fmt.Sprintf("fetch-%d", f.nextId), Status: FetchJobRunning}
+		key := bucketName + "?fetch&jobId=" + job.JobId
+		f.configs[key], _ = json.Marshal(job)
+		f.mu.Unlock()
+		go func() {
+			if err := download(); err != nil {
+				job.Status, job.Code, job.Message = FetchJobFailed, "FetchObjectFailed", err.Error()
+			} else {
+				job.Status = FetchJobSucceeded
+			}
+			f.mu.Lock()
+			f.configs[key], _ = json.Marshal(job)
+			f.mu.Unlock()
+		}()
+		f.reply(w, FetchObjectResponse{Code: "success", Message: "success", RequestId: "fake", JobId: job.JobId})
+		return
+	}
+	if err := download(); err != nil {
+		f.reply(w, FetchObjectResponse{Code: "FetchObjectFailed", Message: err.Error(), RequestId: "fake"})
+		return
+	}
+	f.reply(w, FetchObjectResponse{Code: "success", Message: "success", RequestId: "fake"})
+}
+
+// restoreObject serves RestoreObject. The restore stays ongoing until the test calls
+// finishRestore.
+func (f *fakeBos) restoreObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string) {
+	obj := f.object(bucketName, objectName)
+	switch {
+	case obj == nil:
+		f.fail(w, http.StatusNotFound, "NoSuchKey", "object does not exist")
+	case obj.header.Get(auth.BCE_STORAGE_CLASS) != StorageClassArchive:
+		f.fail(w, http.StatusBadRequest, "InvalidObjectState", "object is not archived")
+	case strings.Contains(obj.header.Get(auth.BCE_RESTORE), `ongoing-request="true"`):
+		f.fail(w, http.StatusConflict, "RestoreAlreadyInProgress", "object is being restored")
+	default:
+		f.setRestore(bucketName, objectName, `ongoing-request="true"`)
+	}
+}
+
+// finishRestore completes the restore of an object, keeping it until expiry.
+func (f *fakeBos) finishRestore(bucketName, objectName string, expiry time.Time) {
+	f.setRestore(bucketName, objectName, fmt.Sprintf(`ongoing-request="false", expiry-date="%s"`,
+		expiry.UTC().Format(http.TimeFormat)))
+}
+
+// setRestore replaces an object with a copy carrying the given restore status, so that
+// readers of the old one are not disturbed.
+func (f *fakeBos) setRestore(bucketName, objectName, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj := *f.objects[bucketName][objectName]
+	obj.header = obj.header.Clone()
+	obj.header.Set(auth.BCE_RESTORE, status)
+	f.objects[bucketName][objectName] = &obj
+}
+
+// evalConditions checks the conditional request headers against obj the way HTTP does,
+// returning 0 when the request may go ahead, or the status to answer it with instead.
+func evalConditions(obj *fakeObject, ifMatch, ifNoneMatch, ifModifiedSince, ifUnmodifiedSince string) int {
+	matches := func(v string) bool {
+		for _, e := range strings.Split(v, ",") {
+			e = strings.TrimSpace(e)
+			if e == "*" || e == obj.eTag || e == "\""+obj.eTag+"\"" {
+				return true
+			}
+		}
+		return false
+	}
+	if ifMatch != "" && !matches(ifMatch) {
+		return http.StatusPreconditionFailed
+	}
+	if t, err := http.ParseTime(ifUnmodifiedSince); err == nil && ifMatch == "" && obj.lastModified.After(t) {
+		return http.StatusPreconditionFailed
+	}
+	if ifNoneMatch != "" && matches(ifNoneMatch) {
+		return http.StatusNotModified
+	}
+	if t, err := http.ParseTime(ifModifiedSince); err == nil && ifNoneMatch == "" && !obj.lastModified.After(t) {
+		return http.StatusNotModified
+	}
+	return 0
+}
+
+// parseFakeRange parses a "bytes=start-[end]" Range header against an object of the
+// given size.
+func parseFakeRange(rng string, size int64) (start, end int64, ok bool) {
+	var err error
+	spec := strings.SplitN(strings.TrimPrefix(rng, "bytes="), "-", 2)
+	if len(spec) != 2 {
+		return 0, 0, false
+	}
+	if start, err = strconv.ParseInt(spec[0], 10, 64); err != nil || start >= size {
+		return 0, 0, false
+	}
+	end = size - 1
+	if spec[1] != "" {
+		if end, err = strconv.ParseInt(spec[1], 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true
+}
+
+func (f *fakeBos) serveMultipart(w http.ResponseWriter, r *http.Request, bucketName, objectName string, body []byte) {
+	query := r.URL.Query()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := query["uploads"]; ok && r.Method == http.MethodPost {
+		f.nextId++
+		uploadId := fmt.Sprintf("upload-%d", f.nextId)
+		f.uploads[uploadId] = &fakeUpload{
+			bucketName: bucketName,
+			objectName: objectName,
+			header:     objectHeader(r),
+			initiated:  time.Now().UTC(),
+			parts:      map[int]*fakeObject{},
+		}
+		f.reply(w, MultipartUploadResponse{BucketName: bucketName, ObjectName: objectName, UploadId: uploadId})
+		return
+	}
+
+	upload := f.uploads[query.Get("uploadId")]
+	if upload == nil || upload.bucketName != bucketName || upload.objectName != objectName {
+		f.fail(w, http.StatusNotFound, "NoSuchUpload", "upload does not exist")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var number int
+		fmt.Sscanf(query.Get("partNumber"), "%d", &number)
+		if number < 1 || number > MaxParts {
+			f.fail(w, http.StatusBadRequest, "InvalidArgument", "bad part number")
+			return
+		}
+		if !customerKeyMatches(upload.header, r, auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY) {
+			f.fail(w, http.StatusForbidden, "AccessDenied", "customer key does not match")
+			return
+		}
+		copied := r.Header.Get(auth.BCE_COPY_SOURCE) != ""
+		if copied {
+			_, data, ok := f.copySource(w, r)
+			if !ok {
+				return
+			}
+			body = append([]byte(nil), data...)
+		}
+		part := &fakeObject{
+			data:         body,
+			eTag:         fmt.Sprintf("%x", md5.Sum(body)),
+			lastModified: time.Now().UTC(),
+		}
+		upload.parts[number] = part
+		if copied {
+			f.reply(w, CopyObjectResponse{LastModified: part.lastModified.Format(time.RFC3339), ETag: part.eTag})
+			return
+		}
+		w.Header().Set("ETag", "\""+part.eTag+"\"")
+	case http.MethodGet:
+		marker := 0
+		fmt.Sscanf(query.Get("partNumberMarker"), "%d", &marker)
+		maxParts := 1000
+		fmt.Sscanf(query.Get("maxParts"), "%d", &maxParts)
+
+		var numbers []int
+		for n := range upload.parts {
+			if n > marker {
+				numbers = append(numbers, n)
+			}
+		}
+		sort.Ints(numbers)
+		res := ListPartsResponse{
+			BucketName:       bucketName,
+			ObjectName:       objectName,
+			UploadId:         query.Get("uploadId"),
+			PartNumberMarker: marker,
+			MaxParts:         maxParts,
+		}
+		for _, n := range numbers {
+			if len(res.Parts) == maxParts {
+				res.IsTruncated = true
+				break
+			}
+			p := upload.parts[n]
+			res.Parts = append(res.Parts, PartInfo{
+				PartNumber:   n,
+				ETag:         p.eTag,
+				LastModified: p.lastModified.Format(time.RFC3339),
+				Size:         len(p.data),
+			})
+			res.NextPartNumberMarker = n
+		}
+		f.reply(w, res)
+	case http.MethodPost:
+		var req struct {
+			Parts []PartInfo `json:"parts"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil || len(req.Parts) == 0 {
+			f.fail(w, http.StatusBadRequest, "MalformedJSON", "bad part list")
+			return
+		}
+		var data []byte
+		for i, p := range req.Parts {
+			part := upload.parts[p.PartNumber]
+			if part == nil || part.eTag != p.ETag || (i > 0 && p.PartNumber <= req.Parts[i-1].PartNumber) {
+				f.fail(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("bad part %d", p.PartNumber))
+				return
+			}
+			if i < len(req.Parts)-1 && len(part.data) < MinPartSize {
+				f.fail(w, http.StatusBadRequest, "EntityTooSmall", fmt.Sprintf("part %d too small", p.PartNumber))
+				return
+			}
+			data = append(data, part.data...)
+		}
+		delete(f.uploads, query.Get("uploadId"))
+		obj := &fakeObject{
+			data:         data,
+			header:       upload.header,
+			eTag:         fmt.Sprintf("%x", md5.Sum(data)),
+			lastModified: time.Now().UTC().Truncate(time.Second),
+		}
+		if f.objects[bucketName] == nil {
+			f.objects[bucketName] = map[string]*fakeObject{}
+		}
+		f.objects[bucketName][objectName] = obj
+		f.reply(w, CompleteMultipartUploadResponse{BucketName: bucketName, ObjectName: objectName, ETag: obj.eTag})
+	case http.MethodDelete:
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// postObject serves a browser form upload, checking the signature and every condition
+// of the policy against the form.
+func (f *fakeBos) postObject(w http.ResponseWriter, r *http.Request, bucketName string, body []byte) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "MalformedPOSTRequest", err.Error())
+		return
+	}
+	form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "MalformedPOSTRequest", err.Error())
+		return
+	}
+	field := func(name string) string {
+		if v := form.Value[name]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	files := form.File["file"]
+	if len(files) != 1 {
+		f.fail(w, http.StatusBadRequest, "MalformedPOSTRequest", "no file")
+		return
+	}
+	file, err := files[0].Open()
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "MalformedPOSTRequest", err.Error())
+		return
+	}
+	data, _ := ioutil.ReadAll(file)
+	file.Close()
+
+	mac := hmac.New(sha256.New, []byte(fakeSecretKey))
+	mac.Write([]byte(field("policy")))
+	if field("accessKey") != fakeAccessKey || field("signature") != hex.EncodeToString(mac.Sum(nil)) {
+		f.fail(w, http.StatusForbidden, "SignatureDoesNotMatch", "bad form signature")
+		return
+	}
+	document, err := base64.StdEncoding.DecodeString(field("policy"))
+	var policy struct {
+		Expiration time.Time         `json:"expiration"`
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	if err == nil {
+		err = json.Unmarshal(document, &policy)
+	}
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "InvalidPolicyDocument", "policy is not base64 JSON")
+		return
+	}
+	if time.Now().After(policy.Expiration) {
+		f.fail(w, http.StatusForbidden, "AccessDenied", "policy expired")
+		return
+	}
+
+	value := func(name string) string {
+		if name == "bucket" {
+			return bucketName
+		}
+		return field(name)
+	}
+	for _, raw := range policy.Conditions {
+		var exact map[string]string
+		var rule []interface{}
+		ok := false
+		if json.Unmarshal(raw, &exact) == nil && len(exact) == 1 {
+			for name, want := range exact {
+				ok = value(name) == want
+			}
+		} else if json.Unmarshal(raw, &rule) == nil && len(rule) == 3 {
+			switch rule[0] {
+			case "starts-with":
+				name, _ := rule[1].(string)
+				prefix, _ := rule[2].(string)
+				ok = strings.HasPrefix(name, "$") && strings.HasPrefix(value(name[1:]), prefix)
+			case "content-length-range":
+				min, _ := rule[1].(float64)
+				max, _ := rule[2].(float64)
+				ok = float64(len(data)) >= min && float64(len(data)) <= max
+			}
+		}
+		if !ok {
+			f.fail(w, http.StatusForbidden, "AccessDenied", "policy condition failed: "+string(raw))
+			return
+		}
+	}
+
+	key := field("key")
+	if key == "" {
+		f.fail(w, http.StatusBadRequest, "MalformedPOSTRequest", "no key")
+		return
+	}
+	header := http.Header{}
+	if ct := field("Content-Type"); ct != "" {
+		header.Set("Content-Type", ct)
+	}
+	obj := f.putObject(bucketName, key, data, header)
+	w.Header().Set("ETag", "\""+obj.eTag+"\"")
+	if redirect := field("success-action-redirect"); redirect != "" {
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
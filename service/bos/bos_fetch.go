@@ -0,0 +1,266 @@
+package bos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+// Modes of FetchObject. In FetchModeSync the call returns once the object is stored;
+// in FetchModeAsync it returns once BOS has accepted the job.
+const (
+	FetchModeSync  = "sync"
+	FetchModeAsync = "async"
+)
+
+const DefaultFetchPollInterval = 2 * time.Second
+
+// States of a FetchJob.
+const (
+	FetchJobRunning   = "running"
+	FetchJobSucceeded = "success"
+	FetchJobFailed    = "failed"
+)
+
+type FetchObjectOptions struct {
+	StorageClass string
+
+	// LocalFallback downloads the source and uploads it with Uploader when BOS cannot
+	// fetch it itself, because the fetch API is not available in the region. The
+	// fallback runs before FetchObject returns, whatever the mode.
+	LocalFallback bool
+
+	// Uploader uploads the object for the fallback; NewUploader(c) when nil.
+	Uploader *Uploader
+
+	// HTTPClient downloads the source for the fallback; http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+type FetchObjectResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestId string `json:"requestId"`
+
+	// JobId identifies an async fetch, for GetFetchJob.
+	JobId string `json:"jobId"`
+
+	// Upload is set when the object was stored by the local fallback.
+	Upload *UploadResult `json:"-"`
+}
+
+/*
+ * Name: FetchObject
+ * URL: http://bce.baidu.com/doc/BOS/API.html#FetchObject.E6.8E.A5.E5.8F.A3
+ */
+
+// FetchObject has BOS download sourceURL, such as a partner's source video, into
+// bucketName/objectName, without the data passing through the caller.
+func (c *BosClient) FetchObject(bucketName, objectName, sourceURL, mode string) (*FetchObjectResponse, error) {
+	return c.FetchObjectWithOptions(bucketName, objectName, sourceURL, mode, nil)
+}
+
+// FetchObjectWithOptions is FetchObject with the storage class and fallback set by opts.
+func (c *BosClient) FetchObjectWithOptions(bucketName, objectName, sourceURL, mode string,
+	opts *FetchObjectOptions) (*FetchObjectResponse, error) {
+
+	if opts == nil {
+		opts = &FetchObjectOptions{}
+	}
+	if mode != FetchModeSync && mode != FetchModeAsync {
+		return nil, fmt.Errorf("fetch mode must be %q or %q, not %q", FetchModeSync, FetchModeAsync, mode)
+	}
+	if u, err := url.Parse(sourceURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("fetch source %q is not an http or https URL", sourceURL)
+	}
+
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method: httplib.POST,
+		Headers: map[string]string{
+			auth.BCE_FETCH_SOURCE: sourceURL,
+			auth.BCE_FETCH_MODE:   mode,
+		},
+		Query: "fetch",
+		Path:  c.APIVersion + "/" + bucketName + "/" + objectName,
+	}
+	if opts.StorageClass != "" {
+		req.Headers[auth.BCE_STORAGE_CLASS] = opts.StorageClass
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		if opts.LocalFallback && fetchUnavailable(err) {
+			return c.fetchLocally(bucketName, objectName, sourceURL, opts)
+		}
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response FetchObjectResponse
+
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	if response.Code != "" && !strings.EqualFold(response.Code, "success") {
+		return nil, &httplib.ErrorResponse{Code: response.Code, Message: response.Message,
+			RequestId: response.RequestId, StatusCode: res.StatusCode}
+	}
+	return &response, nil
+}
+
+// fetchUnavailable reports whether a fetch failed because BOS does not offer it, rather
+// than because of the source or the request.
+func fetchUnavailable(err error) bool {
+	var e *httplib.ErrorResponse
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.StatusCode == http.StatusNotImplemented || e.StatusCode == http.StatusMethodNotAllowed ||
+		e.Code == "NotImplemented"
+}
+
+// fetchLocally streams sourceURL into the object with the uploader, in parts sized for
+// the length of the source when it is known.
+func (c *BosClient) fetchLocally(bucketName, objectName, sourceURL string, opts *FetchObjectOptions) (*FetchObjectResponse, error) {
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Get(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("fetch source %s returned %s", sourceURL, res.Status)
+	}
+
+	uploader := NewUploader(c)
+	if opts.Uploader != nil {
+		u := *opts.Uploader
+		uploader = &u
+	}
+	if res.ContentLength > 0 {
+		if uploader.PartSize, err = uploader.partSize(res.ContentLength); err != nil {
+			return nil, err
+		}
+	}
+	put := &PutObjectOptions{StorageClass: opts.StorageClass}
+	if t := res.Header.Get(httplib.CONTENT_TYPE); t != httplib.OCTET_STREAM {
+		put.ContentType = t
+	}
+
+	result, err := uploader.Upload(bucketName, objectName, &fetchBody{r: res.Body, size: res.ContentLength}, put)
+	if err != nil {
+		return nil, err
+	}
+	return &FetchObjectResponse{Upload: result}, nil
+}
+
+// fetchBody fails the read that ends the source short of its Content-Length, so that
+// a broken download is not stored as a truncated object.
+type fetchBody struct {
+	r    io.Reader
+	n    int64
+	size int64
+}
+
+func (b *fetchBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if (err == io.EOF || err == io.ErrUnexpectedEOF) && b.size >= 0 && b.n != b.size {
+		err = fmt.Errorf("fetch source ended after %d of %d bytes", b.n, b.size)
+	}
+	return n, err
+}
+
+// FetchJob is the state of an async fetch. Code and Message say why a failed job
+// failed, such as the source answering 404.
+type FetchJob struct {
+	JobId   string `json:"jobId"`
+	Status  string `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+/*
+ * Name: GetFetchJob
+ * URL: http://bce.baidu.com/doc/BOS/API.html#GetFetchJob.E6.8E.A5.E5.8F.A3
+ */
+
+func (c *BosClient) GetFetchJob(bucketName, jobId string) (output *FetchJob, err error) {
+	req := &httplib.Request{
+		Method:  httplib.GET,
+		Headers: map[string]string{},
+		Query:   "fetch&jobId=" + url.QueryEscape(jobId),
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response FetchJob
+
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	return &response, nil
+}
+
+// WaitFetchObject waits for a fetch to finish and returns the metadata of the fetched
+// object. The job of an async fetch is polled every interval, or
+// DefaultFetchPollInterval, until it succeeds or fails; a failed job is returned as an
+// *httplib.ErrorResponse carrying its code and message. A sync or fallback fetch has
+// already finished, and its object is read at once.
+func (c *BosClient) WaitFetchObject(ctx context.Context, bucketName, objectName string, fetched *FetchObjectResponse,
+	interval time.Duration) (*ObjectMeta, error) {
+
+	if interval <= 0 {
+		interval = DefaultFetchPollInterval
+	}
+	for fetched.Upload == nil && fetched.JobId != "" {
+		job, err := c.GetFetchJob(bucketName, fetched.JobId)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status == FetchJobSucceeded {
+			break
+		}
+		if job.Status == FetchJobFailed {
+			code := job.Code
+			if code == "" {
+				code = "FetchObjectFailed"
+			}
+			return nil, &httplib.ErrorResponse{Code: code, Message: job.Message, RequestId: fetched.RequestId}
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return c.GetObjectMeta(bucketName, objectName)
+}
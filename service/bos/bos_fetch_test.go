@@ -0,0 +1,124 @@
+package bos
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+// newFetchSource serves content as video/mp4 at /source.mp4. /short.mp4 claims twice
+// the length of what it sends.
+func newFetchSource(t *testing.T, content []byte) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/source.mp4", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Write(content)
+	})
+	mux.HandleFunc("/short.mp4", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(2*len(content)))
+		w.Write(content)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchObject(t *testing.T) {
+	f, c := newFakeBos(t)
+	content := randomContent(t, 4096)
+	source := newFetchSource(t, content)
+
+	res, err := c.FetchObjectWithOptions(TestBukketName, "/ingest/sync.mp4", source.URL+"/source.mp4", FetchModeSync,
+		&FetchObjectOptions{StorageClass: StorageClassStandardIA})
+	if err != nil || res.Upload != nil {
+		t.Fatalf("FetchObject failed. %+v, %v", res, err)
+	}
+	req := f.lastRequest(http.MethodPost)
+	if req.Header.Get(auth.BCE_FETCH_SOURCE) != source.URL+"/source.mp4" || req.Header.Get(auth.BCE_FETCH_MODE) != FetchModeSync {
+		t.Errorf("FetchObject sent headers %v", req.Header)
+	}
+	obj := f.object(TestBukketName, "ingest/sync.mp4")
+	if obj == nil || !bytes.Equal(obj.data, content) || obj.header.Get(auth.BCE_STORAGE_CLASS) != StorageClassStandardIA {
+		t.Fatalf("sync fetch did not store the object")
+	}
+
+	res, err = c.FetchObject(TestBukketName, "ingest/async.mp4", source.URL+"/source.mp4", FetchModeAsync)
+	if err != nil || res.JobId == "" {
+		t.Fatalf("async FetchObject returned %+v, %v", res, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	meta, err := c.WaitFetchObject(ctx, TestBukketName, "ingest/async.mp4", res, 10*time.Millisecond)
+	if err != nil || meta.Size != int64(len(content)) || meta.ContentType != "video/mp4" {
+		t.Errorf("WaitFetchObject returned %+v, %v", meta, err)
+	}
+
+	if _, err = c.FetchObject(TestBukketName, "ingest/missing.mp4", source.URL+"/missing.mp4", FetchModeSync); err == nil {
+		t.Errorf("FetchObject of a missing source should fail")
+	}
+	res, err = c.FetchObject(TestBukketName, "ingest/missing.mp4", source.URL+"/missing.mp4", FetchModeAsync)
+	if err != nil {
+		t.Fatalf("async FetchObject failed. %v", err)
+	}
+	_, err = c.WaitFetchObject(ctx, TestBukketName, "ingest/missing.mp4", res, 10*time.Millisecond)
+	if e, ok := err.(*httplib.ErrorResponse); !ok || e.Code != "FetchObjectFailed" {
+		t.Errorf("WaitFetchObject of a failed job returned %v", err)
+	}
+	if job, err := c.GetFetchJob(TestBukketName, res.JobId); err != nil || job.Status != FetchJobFailed || job.Message == "" {
+		t.Errorf("GetFetchJob returned %+v, %v", job, err)
+	}
+	for _, args := range [][2]string{
+		{source.URL + "/source.mp4", "later"},
+		{"ftp://example.com/source.mp4", FetchModeSync},
+		{"/source.mp4", FetchModeSync},
+	} {
+		if _, err = c.FetchObject(TestBukketName, "bad.mp4", args[0], args[1]); err == nil {
+			t.Errorf("FetchObject accepted source %q in mode %q", args[0], args[1])
+		}
+	}
+}
+
+func TestFetchObjectLocalFallback(t *testing.T) {
+	f, c := newFakeBos(t)
+	content := randomContent(t, MinPartSize+1000)
+	source := newFetchSource(t, content)
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if _, ok := r.URL.Query()["fetch"]; ok {
+			f.fail(w, http.StatusNotImplemented, "NotImplemented", "fetch is not available")
+			return true
+		}
+		return false
+	}
+
+	if _, err := c.FetchObject(TestBukketName, "ingest/a.mp4", source.URL+"/source.mp4", FetchModeSync); err == nil {
+		t.Fatalf("FetchObject without a fallback should fail")
+	}
+
+	opts := &FetchObjectOptions{LocalFallback: true, Uploader: &Uploader{Client: c, PartSize: MinPartSize, Concurrency: 2}}
+	res, err := c.FetchObjectWithOptions(TestBukketName, "ingest/a.mp4", source.URL+"/source.mp4", FetchModeAsync, opts)
+	if err != nil || res.Upload == nil || res.Upload.UploadId == "" || res.Upload.Size != int64(len(content)) {
+		t.Fatalf("FetchObject fallback returned %+v, %v", res, err)
+	}
+	obj := f.object(TestBukketName, "ingest/a.mp4")
+	if obj == nil || !bytes.Equal(obj.data, content) || obj.header.Get("Content-Type") != "video/mp4" {
+		t.Fatalf("fallback did not store the object")
+	}
+	if meta, err := c.WaitFetchObject(context.Background(), TestBukketName, "ingest/a.mp4", res, 0); err != nil ||
+		meta.Size != int64(len(content)) {
+		t.Errorf("WaitFetchObject after the fallback returned %+v, %v", meta, err)
+	}
+
+	if _, err = c.FetchObjectWithOptions(TestBukketName, "ingest/short.mp4", source.URL+"/short.mp4", FetchModeSync, opts); err == nil {
+		t.Errorf("fallback accepted a truncated source")
+	}
+	if f.object(TestBukketName, "ingest/short.mp4") != nil {
+		t.Errorf("fallback stored a truncated source")
+	}
+}
@@ -0,0 +1,291 @@
+package bos
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+const DefaultFSCacheTTL = 5 * time.Second
+
+// BucketFS is a read-only fs.FS over the objects of a bucket, or of the part of it under
+// a prefix, so that BOS content can be served with http.FileServer(http.FS(fsys)) or
+// read by code written against io/fs. Keys are split into directories on "/"; the
+// placeholder objects some tools create for empty directories, with keys ending in "/",
+// are shown as such directories. Keys that are not valid fs paths, such as "a//b", are
+// left out of directory listings.
+//
+// Open files are ObjectReaders, which implement io.ReaderAt and io.Seeker. A BucketFS
+// is safe for concurrent use.
+type BucketFS struct {
+	Client     *BosClient
+	BucketName string
+
+	// CacheTTL is how long directory listings are kept before being fetched again. Zero
+	// means DefaultFSCacheTTL, and a negative value disables the cache.
+	CacheTTL time.Duration
+
+	// ReaderOptions tunes the reads of open files.
+	ReaderOptions *ObjectReaderOptions
+
+	root string
+
+	mu   sync.Mutex
+	dirs map[string]*fsDirListing
+}
+
+// fsDirListing is a cached directory listing.
+type fsDirListing struct {
+	entries []fs.DirEntry
+	expires time.Time
+}
+
+// NewBucketFS returns a file system rooted at prefix, which is the whole bucket when
+// empty.
+func NewBucketFS(c *BosClient, bucketName, prefix string) *BucketFS {
+	fsys := &BucketFS{
+		Client:     c,
+		BucketName: bucketName,
+		CacheTTL:   DefaultFSCacheTTL,
+		dirs:       map[string]*fsDirListing{},
+	}
+	if prefix = strings.Trim(c.formatPath(prefix), "/"); prefix != "" {
+		fsys.root = prefix + "/"
+	}
+	return fsys
+}
+
+// key returns the object key of the file called name.
+func (fsys *BucketFS) key(name string) string {
+	if name == "." {
+		return fsys.root
+	}
+	return fsys.root + name
+}
+
+// Open opens the named file or directory.
+func (fsys *BucketFS) Open(name string) (fs.File, error) {
+	info, err := fsys.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return &fsFile{
+			ObjectReader: newObjectReader(fsys.Client, fsys.BucketName, fsys.key(name), info.meta, fsys.ReaderOptions),
+			info:         info,
+		}, nil
+	}
+	entries, err := fsys.readDir("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return &fsDir{info: info, entries: entries}, nil
+}
+
+// Stat returns a FileInfo describing the named file or directory. The Sys method of
+// the FileInfo of a file returns its *ObjectMeta.
+func (fsys *BucketFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := fsys.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ReadDir lists the named directory, sorted by name.
+func (fsys *BucketFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fsys.readDir("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+// stat looks for a file with HEAD first, and then for a directory.
+func (fsys *BucketFS) stat(op, name string) (*fsFileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	dir := &fsFileInfo{name: path.Base(name), mode: fs.ModeDir | 0555}
+	if name == "." {
+		return dir, nil
+	}
+
+	meta, err := fsys.Client.GetObjectMeta(fsys.BucketName, fsys.key(name))
+	if err == nil {
+		return &fsFileInfo{
+			name:    path.Base(name),
+			size:    meta.Size,
+			mode:    0444,
+			modTime: meta.LastModified,
+			meta:    meta,
+		}, nil
+	}
+	if !isNotFound(err) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if _, err = fsys.readDir(op, name); err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+// readDir returns the cached listing of the named directory, listing it again once it
+// has expired. The entries must not be modified.
+func (fsys *BucketFS) readDir(op, name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	ttl := fsys.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultFSCacheTTL
+	}
+	fsys.mu.Lock()
+	cached := fsys.dirs[name]
+	fsys.mu.Unlock()
+	if cached != nil && time.Now().Before(cached.expires) {
+		return cached.entries, nil
+	}
+
+	entries, err := fsys.listDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if ttl > 0 {
+		fsys.mu.Lock()
+		if fsys.dirs == nil {
+			fsys.dirs = map[string]*fsDirListing{}
+		}
+		fsys.dirs[name] = &fsDirListing{entries: entries, expires: time.Now().Add(ttl)}
+		fsys.mu.Unlock()
+	}
+	return entries, nil
+}
+
+// listDir lists the named directory with a delimiter listing. A directory with no keys
+// under it only exists when it has a placeholder object.
+func (fsys *BucketFS) listDir(name string) ([]fs.DirEntry, error) {
+	prefix := fsys.key(name)
+	if name != "." {
+		prefix += "/"
+	}
+	listing, err := fsys.Client.ListDirectory(fsys.BucketName, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]bool{}
+	entries := []fs.DirEntry{}
+	for i := range listing.Objects {
+		obj := &listing.Objects[i]
+		base := strings.TrimPrefix(obj.ObjectName, prefix)
+		if !validName(base) {
+			continue
+		}
+		files[base] = true
+		entries = append(entries, &fsFileInfo{
+			name:    base,
+			size:    obj.Size,
+			mode:    0444,
+			modTime: obj.LastModified,
+			meta:    &obj.ObjectMeta,
+		})
+	}
+	for _, p := range listing.Prefixes {
+		// An object and a directory of the same name show as the object, as Stat does.
+		base := strings.TrimSuffix(strings.TrimPrefix(p, prefix), "/")
+		if validName(base) && !files[base] {
+			entries = append(entries, &fsFileInfo{name: base, mode: fs.ModeDir | 0555})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	if len(listing.Objects) == 0 && len(listing.Prefixes) == 0 && name != "." {
+		if _, err = fsys.Client.GetObjectMeta(fsys.BucketName, prefix); err != nil {
+			if isNotFound(err) {
+				return nil, fs.ErrNotExist
+			}
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// validName reports whether name can be a single element of an fs path.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+func isNotFound(err error) bool {
+	var e *httplib.ErrorResponse
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// fsFileInfo describes a file or a directory of a BucketFS, as both an fs.FileInfo and
+// an fs.DirEntry.
+type fsFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	meta    *ObjectMeta
+}
+
+func (i *fsFileInfo) Name() string               { return i.name }
+func (i *fsFileInfo) Size() int64                { return i.size }
+func (i *fsFileInfo) Mode() fs.FileMode          { return i.mode }
+func (i *fsFileInfo) ModTime() time.Time         { return i.modTime }
+func (i *fsFileInfo) IsDir() bool                { return i.mode.IsDir() }
+func (i *fsFileInfo) Type() fs.FileMode          { return i.mode.Type() }
+func (i *fsFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+func (i *fsFileInfo) Sys() interface{} {
+	if i.meta == nil {
+		return nil
+	}
+	return i.meta
+}
+
+// fsDir is an open directory.
+type fsDir struct {
+	info    *fsFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n > 0 {
+		if len(rest) == 0 {
+			return nil, io.EOF
+		}
+		if n < len(rest) {
+			rest = rest[:n]
+		}
+	}
+	d.offset += len(rest)
+	return append([]fs.DirEntry(nil), rest...), nil
+}
+
+// fsFile is an open file.
+type fsFile struct {
+	*ObjectReader
+	info *fsFileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
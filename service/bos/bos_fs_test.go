@@ -0,0 +1,120 @@
+package bos
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func putSite(f *fakeBos) {
+	f.putObject(TestBukketName, "site/index.html", []byte("<html>home</html>"), http.Header{"Content-Type": {"text/html"}})
+	f.putObject(TestBukketName, "site/css/main.css", []byte("body { color: black }"), nil)
+	f.putObject(TestBukketName, "site/video/hls/master.m3u8", []byte("#EXTM3U\n"), nil)
+	f.putObject(TestBukketName, "site/video/hls/seg-001.ts", []byte(strings.Repeat("0123456789", 100)), nil)
+	f.putObject(TestBukketName, "site/empty/", nil, nil)
+	f.putObject(TestBukketName, "site/bad//name", []byte("skipped"), nil)
+	f.putObject(TestBukketName, "outside.txt", []byte("not in the file system"), nil)
+}
+
+func TestBucketFS(t *testing.T) {
+	f, c := newFakeBos(t)
+	putSite(f)
+
+	fsys := NewBucketFS(c, TestBukketName, "/site/")
+	if err := fstest.TestFS(fsys, "index.html", "css/main.css", "video/hls/master.m3u8", "video/hls/seg-001.ts", "empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fsys.Open("outside.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open outside the root returned %v", err)
+	}
+	if _, err := fsys.Open("../outside.txt"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Open of an invalid path returned %v", err)
+	}
+	info, err := fsys.Stat("index.html")
+	if err != nil || info.Sys().(*ObjectMeta).ContentType != "text/html" {
+		t.Errorf("Stat failed. %v %+v", err, info)
+	}
+
+	file, err := fsys.Open("video/hls/seg-001.ts")
+	if err != nil {
+		t.Fatalf("Open failed. %v", err)
+	}
+	defer file.Close()
+	rs := file.(io.ReadSeeker)
+	if _, err = rs.Seek(-15, io.SeekEnd); err != nil {
+		t.Fatalf("Seek failed. %v", err)
+	}
+	data, err := ioutil.ReadAll(rs)
+	if err != nil || string(data) != "567890123456789" {
+		t.Errorf("read after Seek returned %q, %v", data, err)
+	}
+	buf := make([]byte, 4)
+	if n, err := file.(io.ReaderAt).ReadAt(buf, 998); n != 2 || err != io.EOF || string(buf[:n]) != "89" {
+		t.Errorf("ReadAt at the end returned %d, %v", n, err)
+	}
+}
+
+func TestBucketFSCache(t *testing.T) {
+	f, c := newFakeBos(t)
+	putSite(f)
+
+	fsys := NewBucketFS(c, TestBukketName, "site")
+	fsys.CacheTTL = 50 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		if _, err := fs.ReadDir(fsys, "video/hls"); err != nil {
+			t.Fatalf("ReadDir failed. %v", err)
+		}
+	}
+	if n := listRequests(f); n != 1 {
+		t.Errorf("ReadDir listed %d times, want 1", n)
+	}
+
+	f.putObject(TestBukketName, "site/video/hls/seg-002.ts", []byte("new"), nil)
+	time.Sleep(60 * time.Millisecond)
+	entries, err := fs.ReadDir(fsys, "video/hls")
+	if err != nil || len(entries) != 3 || listRequests(f) != 2 {
+		t.Errorf("ReadDir after the cache expired returned %v, %d entries", err, len(entries))
+	}
+}
+
+func TestBucketFSFileServer(t *testing.T) {
+	f, c := newFakeBos(t)
+	putSite(f)
+
+	server := httptest.NewServer(http.FileServer(http.FS(NewBucketFS(c, TestBukketName, "site"))))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(data) != "<html>home</html>" {
+		t.Errorf("GET / returned %d %q", res.StatusCode, data)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/video/hls/seg-001.ts", nil)
+	req.Header.Set("Range", "bytes=10-14")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusPartialContent || string(data) != "01234" {
+		t.Errorf("ranged GET returned %d %q", res.StatusCode, data)
+	}
+
+	if res, err = http.Get(server.URL + "/missing.html"); err != nil || res.StatusCode != http.StatusNotFound {
+		t.Errorf("GET of a missing file returned %v %v", err, res)
+	}
+}
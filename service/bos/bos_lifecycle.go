@@ -0,0 +1,272 @@
+package bos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+const (
+	LifecycleStatusEnabled  = "enabled"
+	LifecycleStatusDisabled = "disabled"
+)
+
+// Lifecycle actions.
+const (
+	LifecycleActionTransition           = "Transition"
+	LifecycleActionDeleteObject         = "DeleteObject"
+	LifecycleActionAbortMultipartUpload = "AbortMultipartUpload"
+)
+
+// LifecycleRule applies Action to the objects matching Resource once Condition holds.
+type LifecycleRule struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+
+	// Resource lists the objects the rule covers, as "<bucket>/<prefix>*" patterns;
+	// see LifecycleResource.
+	Resource  []string           `json:"resource"`
+	Condition LifecycleCondition `json:"condition"`
+	Action    LifecycleAction    `json:"action"`
+}
+
+type LifecycleCondition struct {
+	Time LifecycleTimeCondition `json:"time"`
+}
+
+// LifecycleTimeCondition holds once the time in DateGreaterThan has passed. It is
+// either relative to the object, as built by LifecycleDaysAfterLastModified, or a fixed
+// date, as built by LifecycleDate.
+type LifecycleTimeCondition struct {
+	DateGreaterThan string `json:"dateGreaterThan"`
+}
+
+type LifecycleAction struct {
+	Name string `json:"name"`
+
+	// StorageClass is the class a Transition moves objects to.
+	StorageClass string `json:"storageClass,omitempty"`
+}
+
+// LifecycleResource returns the resource pattern covering every object of the bucket
+// whose key starts with prefix.
+func LifecycleResource(bucketName, prefix string) string {
+	return bucketName + "/" + strings.TrimPrefix(prefix, "/") + "*"
+}
+
+// LifecycleDaysAfterLastModified returns a time condition that holds days after an
+// object was last modified.
+func LifecycleDaysAfterLastModified(days int) string {
+	return fmt.Sprintf("$(lastModified)+P%dD", days)
+}
+
+// LifecycleDate returns a time condition that holds from the given day on.
+func LifecycleDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02") + "T00:00:00Z"
+}
+
+var lifecycleDaysPattern = regexp.MustCompile(`^\$\(lastModified\)\+P(\d+)D$`)
+
+// lifecycleDays returns the number of days of a relative time condition, or -1 when it
+// is a fixed date.
+func lifecycleDays(cond string) (int, error) {
+	if m := lifecycleDaysPattern.FindStringSubmatch(cond); m != nil {
+		days, err := strconv.Atoi(m[1])
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("time condition %q needs a positive number of days", cond)
+		}
+		return days, nil
+	}
+	if t, err := time.Parse(time.RFC3339, cond); err == nil && t.Equal(t.Truncate(24*time.Hour)) {
+		return -1, nil
+	}
+	return 0, fmt.Errorf("time condition %q is neither $(lastModified)+P<n>D nor a date at midnight UTC", cond)
+}
+
+// storageClassRank orders the storage classes from hottest to coldest.
+var storageClassRank = map[string]int{
+	StorageClassStandard:   0,
+	StorageClassStandardIA: 1,
+	StorageClassCold:       2,
+	StorageClassArchive:    3,
+}
+
+// ValidateLifecycleRules checks rules for mistakes BOS would reject, or accept with a
+// surprising result: missing or duplicate IDs, resources outside bucketName, malformed
+// conditions, and, among the enabled rules on the same resource, transitions that do
+// not go to colder classes over time or that come after the objects are deleted.
+func ValidateLifecycleRules(bucketName string, rules []LifecycleRule) error {
+	if len(rules) == 0 {
+		return fmt.Errorf("lifecycle configuration needs at least one rule")
+	}
+
+	type step struct {
+		rule string
+		days int
+		rank int
+	}
+	ids := map[string]bool{}
+	transitions := map[string][]step{}
+	deletes := map[string]step{}
+
+	for _, r := range rules {
+		if r.Id == "" {
+			return fmt.Errorf("lifecycle rule needs an id")
+		}
+		if ids[r.Id] {
+			return fmt.Errorf("lifecycle rule id %q is used twice", r.Id)
+		}
+		ids[r.Id] = true
+
+		if r.Status != LifecycleStatusEnabled && r.Status != LifecycleStatusDisabled {
+			return fmt.Errorf("lifecycle rule %q: status %q is neither %s nor %s", r.Id, r.Status,
+				LifecycleStatusEnabled, LifecycleStatusDisabled)
+		}
+		if len(r.Resource) == 0 {
+			return fmt.Errorf("lifecycle rule %q needs a resource", r.Id)
+		}
+		for _, res := range r.Resource {
+			if !strings.HasPrefix(res, bucketName+"/") {
+				return fmt.Errorf("lifecycle rule %q: resource %q is not in bucket %s", r.Id, res, bucketName)
+			}
+		}
+		days, err := lifecycleDays(r.Condition.Time.DateGreaterThan)
+		if err != nil {
+			return fmt.Errorf("lifecycle rule %q: %v", r.Id, err)
+		}
+
+		rank := 0
+		switch r.Action.Name {
+		case LifecycleActionTransition:
+			var ok bool
+			if rank, ok = storageClassRank[r.Action.StorageClass]; !ok || rank == 0 {
+				return fmt.Errorf("lifecycle rule %q: cannot transition to storage class %q", r.Id, r.Action.StorageClass)
+			}
+		case LifecycleActionDeleteObject, LifecycleActionAbortMultipartUpload:
+			if r.Action.StorageClass != "" {
+				return fmt.Errorf("lifecycle rule %q: %s takes no storage class", r.Id, r.Action.Name)
+			}
+		default:
+			return fmt.Errorf("lifecycle rule %q: unknown action %q", r.Id, r.Action.Name)
+		}
+
+		// Only relative conditions of enabled rules can be compared with one another.
+		if r.Status != LifecycleStatusEnabled || days < 0 {
+			continue
+		}
+		for _, res := range r.Resource {
+			s := step{rule: r.Id, days: days, rank: rank}
+			switch r.Action.Name {
+			case LifecycleActionTransition:
+				transitions[res] = append(transitions[res], s)
+			case LifecycleActionDeleteObject:
+				if d, ok := deletes[res]; ok {
+					return fmt.Errorf("lifecycle rules %q and %q both delete %s", d.rule, r.Id, res)
+				}
+				deletes[res] = s
+			}
+		}
+	}
+
+	for res, steps := range transitions {
+		for i, a := range steps {
+			if d, ok := deletes[res]; ok && a.days >= d.days {
+				return fmt.Errorf("lifecycle rule %q moves %s after rule %q has deleted it", a.rule, res, d.rule)
+			}
+			for _, b := range steps[i+1:] {
+				if a.rank == b.rank || a.days == b.days || (a.days < b.days) != (a.rank < b.rank) {
+					return fmt.Errorf("lifecycle rules %q and %q conflict: transitions of %s must reach colder "+
+						"storage classes on later days", a.rule, b.rule, res)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+/*
+ * Name: PutBucketLifecycle
+ * URL: http://bce.baidu.com/doc/BOS/API.html#PutBucketLifecycle.E6.8E.A5.E5.8F.A3
+ */
+
+// PutBucketLifecycle replaces the lifecycle rules of a bucket, after checking them with
+// ValidateLifecycleRules.
+func (c *BosClient) PutBucketLifecycle(bucketName string, rules []LifecycleRule) (err error) {
+	if err = ValidateLifecycleRules(bucketName, rules); err != nil {
+		return
+	}
+	jstring, err := json.Marshal(map[string][]LifecycleRule{"rule": rules})
+	if err != nil {
+		return
+	}
+
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{},
+		Query:   "lifecycle",
+		Path:    c.APIVersion + "/" + bucketName,
+		Body:    bytes.NewReader(jstring),
+		Type:    httplib.JSON,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+/*
+ * Name: GetBucketLifecycle
+ * URL: http://bce.baidu.com/doc/BOS/API.html#GetBucketLifecycle.E6.8E.A5.E5.8F.A3
+ */
+
+type BucketLifecycleResponse struct {
+	Rule []LifecycleRule `json:"rule"`
+}
+
+func (c *BosClient) GetBucketLifecycle(bucketName string) (output *BucketLifecycleResponse, err error) {
+	req := &httplib.Request{
+		Method:  httplib.GET,
+		Headers: map[string]string{},
+		Query:   "lifecycle",
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response BucketLifecycleResponse
+
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	return &response, nil
+}
+
+/*
+ * Name: DeleteBucketLifecycle
+ * URL: http://bce.baidu.com/doc/BOS/API.html#DeleteBucketLifecycle.E6.8E.A5.E5.8F.A3
+ */
+
+func (c *BosClient) DeleteBucketLifecycle(bucketName string) (err error) {
+	req := &httplib.Request{
+		Method:  httplib.DELETE,
+		Headers: map[string]string{},
+		Query:   "lifecycle",
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
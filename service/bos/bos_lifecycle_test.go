@@ -0,0 +1,127 @@
+package bos
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rawUploadRules moves raw uploads to cold storage after 30 days and deletes them
+// after 180.
+func rawUploadRules() []LifecycleRule {
+	resource := []string{LifecycleResource(TestBukketName, "raw/")}
+	after := func(days int) LifecycleCondition {
+		return LifecycleCondition{Time: LifecycleTimeCondition{DateGreaterThan: LifecycleDaysAfterLastModified(days)}}
+	}
+	return []LifecycleRule{
+		{
+			Id:        "raw-to-cold",
+			Status:    LifecycleStatusEnabled,
+			Resource:  resource,
+			Condition: after(30),
+			Action:    LifecycleAction{Name: LifecycleActionTransition, StorageClass: StorageClassCold},
+		},
+		{
+			Id:        "raw-delete",
+			Status:    LifecycleStatusEnabled,
+			Resource:  resource,
+			Condition: after(180),
+			Action:    LifecycleAction{Name: LifecycleActionDeleteObject},
+		},
+		{
+			Id:        "abort-stale-uploads",
+			Status:    LifecycleStatusEnabled,
+			Resource:  []string{LifecycleResource(TestBukketName, "")},
+			Condition: after(7),
+			Action:    LifecycleAction{Name: LifecycleActionAbortMultipartUpload},
+		},
+	}
+}
+
+func TestBucketLifecycle(t *testing.T) {
+	f, c := newFakeBos(t)
+
+	rules := rawUploadRules()
+	if err := c.PutBucketLifecycle(TestBukketName, rules); err != nil {
+		t.Fatalf("PutBucketLifecycle failed. %v", err)
+	}
+	f.mu.Lock()
+	body := string(f.configs[TestBukketName+"?lifecycle"])
+	f.mu.Unlock()
+	for _, want := range []string{`"rule":[`, `"resource":["` + TestBukketName + `/raw/*"]`,
+		`"dateGreaterThan":"$(lastModified)+P30D"`, `"storageClass":"COLD"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("PutBucketLifecycle body %s lacks %s", body, want)
+		}
+	}
+
+	res, err := c.GetBucketLifecycle(TestBukketName)
+	if err != nil || !reflect.DeepEqual(res.Rule, rules) {
+		t.Errorf("GetBucketLifecycle failed. %v %+v", err, res)
+	}
+
+	if err = c.DeleteBucketLifecycle(TestBukketName); err != nil {
+		t.Fatalf("DeleteBucketLifecycle failed. %v", err)
+	}
+	if _, err = c.GetBucketLifecycle(TestBukketName); err == nil {
+		t.Errorf("GetBucketLifecycle should fail after DeleteBucketLifecycle")
+	}
+}
+
+func TestValidateLifecycleRules(t *testing.T) {
+	if err := ValidateLifecycleRules(TestBukketName, rawUploadRules()); err != nil {
+		t.Fatalf("ValidateLifecycleRules rejected valid rules. %v", err)
+	}
+
+	dated := rawUploadRules()[:1]
+	dated[0].Condition.Time.DateGreaterThan = LifecycleDate(time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC))
+	if err := ValidateLifecycleRules(TestBukketName, dated); err != nil {
+		t.Errorf("ValidateLifecycleRules rejected a dated rule. %v", err)
+	}
+
+	for name, change := range map[string]func(r []LifecycleRule) []LifecycleRule{
+		"no rules":     func(r []LifecycleRule) []LifecycleRule { return nil },
+		"missing id":   func(r []LifecycleRule) []LifecycleRule { r[0].Id = ""; return r },
+		"duplicate id": func(r []LifecycleRule) []LifecycleRule { r[1].Id = r[0].Id; return r },
+		"bad status":   func(r []LifecycleRule) []LifecycleRule { r[0].Status = "on"; return r },
+		"no resource":  func(r []LifecycleRule) []LifecycleRule { r[0].Resource = nil; return r },
+		"other bucket": func(r []LifecycleRule) []LifecycleRule { r[0].Resource = []string{"other/raw/*"}; return r },
+		"zero days": func(r []LifecycleRule) []LifecycleRule {
+			r[0].Condition.Time.DateGreaterThan = "$(lastModified)+P0D"
+			return r
+		},
+		"bad date":          func(r []LifecycleRule) []LifecycleRule { r[0].Condition.Time.DateGreaterThan = "2030-01-02"; return r },
+		"unknown action":    func(r []LifecycleRule) []LifecycleRule { r[0].Action.Name = "Archive"; return r },
+		"to standard":       func(r []LifecycleRule) []LifecycleRule { r[0].Action.StorageClass = StorageClassStandard; return r },
+		"delete with class": func(r []LifecycleRule) []LifecycleRule { r[1].Action.StorageClass = StorageClassCold; return r },
+		"move after delete": func(r []LifecycleRule) []LifecycleRule {
+			r[0].Condition.Time.DateGreaterThan = LifecycleDaysAfterLastModified(200)
+			return r
+		},
+		"warmer later": func(r []LifecycleRule) []LifecycleRule {
+			ia := r[0]
+			ia.Id = "raw-to-ia"
+			ia.Action.StorageClass = StorageClassStandardIA
+			ia.Condition.Time.DateGreaterThan = LifecycleDaysAfterLastModified(60)
+			return append(r, ia)
+		},
+		"two deletes": func(r []LifecycleRule) []LifecycleRule {
+			d := r[1]
+			d.Id = "raw-delete-2"
+			return append(r, d)
+		},
+	} {
+		if err := ValidateLifecycleRules(TestBukketName, change(rawUploadRules())); err == nil {
+			t.Errorf("ValidateLifecycleRules accepted rules with %s", name)
+		}
+	}
+
+	// Disabled rules are not compared with the enabled ones.
+	rules := rawUploadRules()
+	rules[0].Status = LifecycleStatusDisabled
+	rules[0].Condition.Time.DateGreaterThan = LifecycleDaysAfterLastModified(200)
+	if err := ValidateLifecycleRules(TestBukketName, rules); err != nil {
+		t.Errorf("ValidateLifecycleRules compared a disabled rule. %v", err)
+	}
+}
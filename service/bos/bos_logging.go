@@ -0,0 +1,107 @@
+package bos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+const (
+	LoggingStatusEnabled  = "enabled"
+	LoggingStatusDisabled = "disabled"
+)
+
+// BucketLogging is where the access logs of a bucket are written: objects in
+// TargetBucket whose keys start with TargetPrefix.
+type BucketLogging struct {
+	// Status is LoggingStatusDisabled, with no target, when logging is off.
+	Status       string `json:"status,omitempty"`
+	TargetBucket string `json:"targetBucket,omitempty"`
+	TargetPrefix string `json:"targetPrefix,omitempty"`
+}
+
+/*
+ * Name: PutBucketLogging
+ * URL: http://bce.baidu.com/doc/BOS/API.html#PutBucketLogging.E6.8E.A5.E5.8F.A3
+ */
+
+// PutBucketLogging turns on the access logs of a bucket, written to targetBucket under
+// targetPrefix. The target bucket must be in the same region and owned by the same
+// user; it may be the bucket itself.
+func (c *BosClient) PutBucketLogging(bucketName, targetBucket, targetPrefix string) (err error) {
+	if targetBucket == "" {
+		return fmt.Errorf("bucket logging needs a target bucket")
+	}
+	jstring, err := json.Marshal(&BucketLogging{
+		TargetBucket: targetBucket,
+		TargetPrefix: c.formatPath(targetPrefix),
+	})
+	if err != nil {
+		return
+	}
+
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{},
+		Query:   "logging",
+		Path:    c.APIVersion + "/" + bucketName,
+		Body:    bytes.NewReader(jstring),
+		Type:    httplib.JSON,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+/*
+ * Name: GetBucketLogging
+ * URL: http://bce.baidu.com/doc/BOS/API.html#GetBucketLogging.E6.8E.A5.E5.8F.A3
+ */
+
+func (c *BosClient) GetBucketLogging(bucketName string) (output *BucketLogging, err error) {
+	req := &httplib.Request{
+		Method:  httplib.GET,
+		Headers: map[string]string{},
+		Query:   "logging",
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response BucketLogging
+
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	return &response, nil
+}
+
+/*
+ * Name: DeleteBucketLogging
+ * URL: http://bce.baidu.com/doc/BOS/API.html#DeleteBucketLogging.E6.8E.A5.E5.8F.A3
+ */
+
+// DeleteBucketLogging turns off the access logs of a bucket. The logs written so far
+// are kept.
+func (c *BosClient) DeleteBucketLogging(bucketName string) (err error) {
+	req := &httplib.Request{
+		Method:  httplib.DELETE,
+		Headers: map[string]string{},
+		Query:   "logging",
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
@@ -0,0 +1,32 @@
+package bos
+
+import (
+	"testing"
+)
+
+func TestBucketLogging(t *testing.T) {
+	_, c := newFakeBos(t)
+
+	logging, err := c.GetBucketLogging(TestBukketName)
+	if err != nil || logging.Status != LoggingStatusDisabled {
+		t.Errorf("GetBucketLogging returned %+v, %v", logging, err)
+	}
+	if err = c.PutBucketLogging(TestBukketName, "logs-bucket", "/access/"+TestBukketName+"/"); err != nil {
+		t.Fatalf("PutBucketLogging failed. %v", err)
+	}
+	logging, err = c.GetBucketLogging(TestBukketName)
+	if err != nil || *logging != (BucketLogging{Status: LoggingStatusEnabled, TargetBucket: "logs-bucket",
+		TargetPrefix: "access/" + TestBukketName + "/"}) {
+		t.Errorf("GetBucketLogging returned %+v, %v", logging, err)
+	}
+	if err = c.DeleteBucketLogging(TestBukketName); err != nil {
+		t.Errorf("DeleteBucketLogging failed. %v", err)
+	}
+	if logging, err = c.GetBucketLogging(TestBukketName); err != nil || logging.Status != LoggingStatusDisabled {
+		t.Errorf("GetBucketLogging after delete returned %+v, %v", logging, err)
+	}
+
+	if err = c.PutBucketLogging(TestBukketName, "", "access/"); err == nil {
+		t.Errorf("PutBucketLogging accepted an empty target bucket")
+	}
+}
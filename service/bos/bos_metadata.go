@@ -0,0 +1,67 @@
+package bos
+
+import (
+	"strings"
+)
+
+// UpdateObjectMetadataOptions tunes UpdateObjectMetadata.
+type UpdateObjectMetadataOptions struct {
+	// ReplaceUserMeta drops the user metadata not in meta. By default meta is merged
+	// into what the object has.
+	ReplaceUserMeta bool
+
+	// Encryption holds the customer key of an object encrypted with one. The object
+	// stays encrypted with the same key.
+	Encryption *ServerSideEncryption
+}
+
+// UpdateObjectMetadata changes the user metadata of an object, such as its title after
+// a video is renamed, without uploading it again: the object is copied onto itself with
+// MetadataDirectiveReplace, keeping its content headers, storage class and server-side
+// encryption. Keys of meta are case-insensitive, and an empty value removes the key.
+//
+// The copy only goes ahead if the object is still the one read at the start, so that a
+// concurrent upload is not overwritten with the old content; the update then fails with
+// a PreconditionFailedError. Objects of up to MaxSingleCopySize are updated with a
+// single copy, which keeps their ETag; larger ones are copied in parts and get a
+// multipart ETag.
+func (c *BosClient) UpdateObjectMetadata(bucketName, objectName string, meta map[string]string,
+	opts *UpdateObjectMetadataOptions) error {
+
+	if opts == nil {
+		opts = &UpdateObjectMetadataOptions{}
+	}
+	current, err := c.GetObjectMetaWithOptions(bucketName, objectName, &GetObjectMetaOptions{Encryption: opts.Encryption})
+	if err != nil {
+		return err
+	}
+
+	headers := sourceObjectOptions(current)
+	userMeta := map[string]string{}
+	if !opts.ReplaceUserMeta {
+		for k, v := range current.UserMeta {
+			userMeta[k] = v
+		}
+	}
+	for k, v := range meta {
+		if k = strings.ToLower(k); v == "" {
+			delete(userMeta, k)
+		} else {
+			userMeta[k] = v
+		}
+	}
+	headers.UserMeta = userMeta
+	if opts.Encryption != nil {
+		headers.Encryption = opts.Encryption
+	} else if current.ServerSideEncryption != "" {
+		headers.Encryption = &ServerSideEncryption{Algorithm: current.ServerSideEncryption, KmsKeyId: current.KmsKeyId}
+	}
+
+	_, err = NewUploader(c).CopyLargeObject(bucketName, objectName, bucketName, objectName, &CopyObjectOptions{
+		CopySourceConditions: CopySourceConditions{IfMatch: current.ETag},
+		MetadataDirective:    MetadataDirectiveReplace,
+		Meta:                 headers,
+		SourceEncryption:     opts.Encryption,
+	})
+	return err
+}
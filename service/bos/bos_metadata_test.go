@@ -0,0 +1,98 @@
+package bos
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+)
+
+func TestUpdateObjectMetadata(t *testing.T) {
+	f, c := newFakeBos(t)
+	_, err := c.PutObjectWithOptions(TestBukketName, "movie.mp4", bytes.NewReader([]byte("movie")), &PutObjectOptions{
+		ContentType:  "video/mp4",
+		CacheControl: "max-age=600",
+		StorageClass: StorageClassStandardIA,
+		UserMeta:     map[string]string{"title": "Draft", "episode": "3"},
+	})
+	if err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+
+	err = c.UpdateObjectMetadata(TestBukketName, "movie.mp4", map[string]string{"Title": "Final", "episode": ""}, nil)
+	if err != nil {
+		t.Fatalf("UpdateObjectMetadata failed. %v", err)
+	}
+	meta, err := c.GetObjectMeta(TestBukketName, "movie.mp4")
+	if err != nil {
+		t.Fatalf("GetObjectMeta failed. %v", err)
+	}
+	if len(meta.UserMeta) != 1 || meta.UserMeta["title"] != "Final" {
+		t.Errorf("UpdateObjectMetadata left user metadata %v", meta.UserMeta)
+	}
+	if meta.ContentType != "video/mp4" || meta.CacheControl != "max-age=600" || meta.StorageClass != StorageClassStandardIA {
+		t.Errorf("UpdateObjectMetadata lost the content headers: %+v", meta)
+	}
+	if string(f.object(TestBukketName, "movie.mp4").data) != "movie" {
+		t.Errorf("UpdateObjectMetadata changed the content")
+	}
+
+	err = c.UpdateObjectMetadata(TestBukketName, "movie.mp4", map[string]string{"director": "Lee"},
+		&UpdateObjectMetadataOptions{ReplaceUserMeta: true})
+	if meta, _ = c.GetObjectMeta(TestBukketName, "movie.mp4"); err != nil || len(meta.UserMeta) != 1 || meta.UserMeta["director"] != "Lee" {
+		t.Errorf("UpdateObjectMetadata with ReplaceUserMeta left %v %v", err, meta.UserMeta)
+	}
+
+	if err = c.UpdateObjectMetadata(TestBukketName, "missing.mp4", map[string]string{"a": "b"}, nil); err == nil {
+		t.Errorf("UpdateObjectMetadata of a missing object should fail")
+	}
+}
+
+func TestUpdateObjectMetadataLargeObject(t *testing.T) {
+	f, c := newFakeBos(t)
+	content := randomContent(t, DefaultPartSize+1000)
+	src := f.putObject(TestBukketName, "movie.mp4", content, http.Header{"X-Bce-Meta-Title": {"Draft"}})
+
+	if err := c.UpdateObjectMetadata(TestBukketName, "movie.mp4", map[string]string{"title": "Final"}, nil); err != nil {
+		t.Fatalf("UpdateObjectMetadata failed. %v", err)
+	}
+	f.mu.Lock()
+	copies := 0
+	for _, r := range f.requests {
+		if r.Header.Get(auth.BCE_COPY_SOURCE) != "" {
+			copies++
+		}
+	}
+	uploads := len(f.uploads)
+	f.mu.Unlock()
+	if copies != 1 || uploads != 0 {
+		t.Errorf("UpdateObjectMetadata sent %d copies and left %d uploads, want a single copy", copies, uploads)
+	}
+	obj := f.object(TestBukketName, "movie.mp4")
+	if obj.eTag != src.eTag || !bytes.Equal(obj.data, content) || obj.header.Get("X-Bce-Meta-Title") != "Final" {
+		t.Errorf("UpdateObjectMetadata changed the ETag to %s or lost the update. %v", obj.eTag, obj.header)
+	}
+}
+
+func TestUpdateObjectMetadataRace(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "movie.mp4", []byte("old"), http.Header{"X-Bce-Meta-Title": {"Old"}})
+
+	// The object is replaced just before the copy.
+	replaced := false
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get(auth.BCE_COPY_SOURCE) != "" && !replaced {
+			replaced = true
+			f.putObject(TestBukketName, "movie.mp4", []byte("new upload"), nil)
+		}
+		return false
+	}
+	err := c.UpdateObjectMetadata(TestBukketName, "movie.mp4", map[string]string{"title": "New"}, nil)
+	if !IsPreconditionFailed(err) {
+		t.Errorf("UpdateObjectMetadata returned %v, want a failed precondition", err)
+	}
+	if string(f.object(TestBukketName, "movie.mp4").data) != "new upload" {
+		t.Errorf("UpdateObjectMetadata overwrote the new upload")
+	}
+}
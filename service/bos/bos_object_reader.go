@@ -0,0 +1,343 @@
+package bos
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+const (
+	DefaultReaderBlockSize   = 1024 * 1024
+	DefaultReadAheadBlocks   = 4
+	DefaultReaderCacheBlocks = 16
+	DefaultReaderMaxRetries  = 3
+)
+
+// ObjectReaderOptions tunes an ObjectReader. The zero value reads blocks of
+// DefaultReaderBlockSize, keeps DefaultReaderCacheBlocks of them, reads
+// DefaultReadAheadBlocks ahead of sequential reads and retries a block
+// DefaultReaderMaxRetries times.
+type ObjectReaderOptions struct {
+	// BlockSize is the size of each ranged request, and of the blocks kept in the cache.
+	BlockSize int64
+
+	// ReadAhead is the number of blocks fetched in the background ahead of sequential
+	// reads. A negative value disables read-ahead.
+	ReadAhead int
+
+	// CacheBlocks is the number of blocks kept, least recently used first out. It is
+	// raised to hold at least the blocks read ahead.
+	CacheBlocks int
+
+	// MaxRetries is how many times a block is requested again after a failed or broken
+	// connection. A negative value disables retries.
+	MaxRetries int
+
+	// Encryption holds the customer key of an object encrypted with one.
+	Encryption *ServerSideEncryption
+}
+
+// ObjectReader reads an object at random, as MP4 probing and ffprobe need, with ranged
+// GetObject requests of a block each. Blocks are cached, and sequential reads fetch the
+// next blocks in the background. Every request asks for the ETag the object had when it
+// was opened, so that replacing the object fails the reads rather than mixing contents.
+//
+// Read and Seek share an offset and are not safe for concurrent use; ReadAt is, and can
+// be used alongside them.
+type ObjectReader struct {
+	client     *BosClient
+	bucketName string
+	objectName string
+	meta       *ObjectMeta
+	opts       ObjectReaderOptions
+
+	mu     sync.Mutex
+	blocks map[int64]*list.Element
+	lru    *list.List
+	closed bool
+
+	offset int64
+
+	// sequential is true while reads follow each other without a Seek elsewhere.
+	sequential bool
+}
+
+// readerBlock is a cached block, or one being fetched until done is closed.
+type readerBlock struct {
+	index int64
+	done  chan struct{}
+	data  []byte
+	err   error
+}
+
+// OpenObject opens an object for random access with the default ObjectReaderOptions.
+func (c *BosClient) OpenObject(bucketName, objectName string) (*ObjectReader, error) {
+	return c.OpenObjectWithOptions(bucketName, objectName, nil)
+}
+
+// OpenObjectWithOptions opens an object for random access. The object's metadata is
+// read once, here; its content is only read as the reader is.
+func (c *BosClient) OpenObjectWithOptions(bucketName, objectName string, opts *ObjectReaderOptions) (*ObjectReader, error) {
+	if opts == nil {
+		opts = &ObjectReaderOptions{}
+	}
+	meta, err := c.GetObjectMetaWithOptions(bucketName, objectName, &GetObjectMetaOptions{Encryption: opts.Encryption})
+	if err != nil {
+		return nil, err
+	}
+	if meta.Size < 0 {
+		return nil, fmt.Errorf("open %s/%s: size unknown", bucketName, objectName)
+	}
+	return newObjectReader(c, bucketName, objectName, meta, opts), nil
+}
+
+func newObjectReader(c *BosClient, bucketName, objectName string, meta *ObjectMeta, opts *ObjectReaderOptions) *ObjectReader {
+	r := &ObjectReader{
+		client:     c,
+		bucketName: bucketName,
+		objectName: c.formatPath(objectName),
+		meta:       meta,
+		blocks:     map[int64]*list.Element{},
+		lru:        list.New(),
+		sequential: true,
+	}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.BlockSize <= 0 {
+		r.opts.BlockSize = DefaultReaderBlockSize
+	}
+	if r.opts.ReadAhead == 0 {
+		r.opts.ReadAhead = DefaultReadAheadBlocks
+	} else if r.opts.ReadAhead < 0 {
+		r.opts.ReadAhead = 0
+	}
+	if r.opts.CacheBlocks <= 0 {
+		r.opts.CacheBlocks = DefaultReaderCacheBlocks
+	}
+	if r.opts.MaxRetries == 0 {
+		r.opts.MaxRetries = DefaultReaderMaxRetries
+	} else if r.opts.MaxRetries < 0 {
+		r.opts.MaxRetries = 0
+	}
+	if r.opts.CacheBlocks < r.opts.ReadAhead+1 {
+		r.opts.CacheBlocks = r.opts.ReadAhead + 1
+	}
+	return r
+}
+
+// Meta returns the metadata of the object when it was opened.
+func (r *ObjectReader) Meta() *ObjectMeta {
+	return r.meta
+}
+
+// Size returns the size of the object.
+func (r *ObjectReader) Size() int64 {
+	return r.meta.Size
+}
+
+// Read reads from the current offset, at most to the end of the block it falls in.
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.meta.Size {
+		if r.isClosed() {
+			return 0, fs.ErrClosed
+		}
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	index := r.offset / r.opts.BlockSize
+	if r.sequential {
+		for i := int64(1); i <= int64(r.opts.ReadAhead); i++ {
+			r.block(index + i)
+		}
+	}
+	data, err := r.wait(r.block(index))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, data[r.offset-index*r.opts.BlockSize:])
+	r.offset += int64(n)
+	r.sequential = true
+	return n, nil
+}
+
+// ReadAt reads len(p) bytes at off, fetching the blocks it spans concurrently. It is
+// safe for concurrent use.
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("read %s/%s: negative offset %d", r.bucketName, r.objectName, off)
+	}
+	if off >= r.meta.Size {
+		if r.isClosed() {
+			return 0, fs.ErrClosed
+		}
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if end > r.meta.Size {
+		end = r.meta.Size
+	}
+	if end == off {
+		return 0, nil
+	}
+
+	first, last := off/r.opts.BlockSize, (end-1)/r.opts.BlockSize
+	blocks := make([]*readerBlock, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		blocks = append(blocks, r.block(i))
+	}
+	n := 0
+	for _, b := range blocks {
+		data, err := r.wait(b)
+		if err != nil {
+			return n, err
+		}
+		start := off + int64(n) - b.index*r.opts.BlockSize
+		n += copy(p[n:end-off], data[start:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek sets the offset of the next Read. Seeking costs nothing until the next Read,
+// which requests the block at the new offset unless it is cached.
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.meta.Size
+	default:
+		return 0, fmt.Errorf("seek %s/%s: bad whence %d", r.bucketName, r.objectName, whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("seek %s/%s: negative position %d", r.bucketName, r.objectName, offset)
+	}
+	if offset != r.offset {
+		r.sequential = false
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// Close drops the cache. Later reads fail; fetches still in flight are let finish.
+func (r *ObjectReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fs.ErrClosed
+	}
+	r.closed = true
+	r.blocks = nil
+	r.lru.Init()
+	return nil
+}
+
+func (r *ObjectReader) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// block returns block index from the cache, starting to fetch it if it is not there. It
+// returns nil past the end of the object or once the reader is closed.
+func (r *ObjectReader) block(index int64) *readerBlock {
+	if index*r.opts.BlockSize >= r.meta.Size {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	if e, ok := r.blocks[index]; ok {
+		r.lru.MoveToFront(e)
+		return e.Value.(*readerBlock)
+	}
+
+	b := &readerBlock{index: index, done: make(chan struct{})}
+	r.blocks[index] = r.lru.PushFront(b)
+	for r.lru.Len() > r.opts.CacheBlocks {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.blocks, oldest.Value.(*readerBlock).index)
+	}
+	go r.fetch(b)
+	return b
+}
+
+// wait returns the data of b once it is fetched. A block that failed is dropped from the
+// cache, so that the next read asks for it again.
+func (r *ObjectReader) wait(b *readerBlock) ([]byte, error) {
+	if b == nil {
+		return nil, fs.ErrClosed
+	}
+	<-b.done
+	if b.err != nil {
+		r.mu.Lock()
+		if e, ok := r.blocks[b.index]; ok && e.Value == b {
+			r.lru.Remove(e)
+			delete(r.blocks, b.index)
+		}
+		r.mu.Unlock()
+		return nil, b.err
+	}
+	return b.data, nil
+}
+
+// fetch reads b with a ranged request, reconnecting when the request fails or the
+// connection breaks. Client errors are not retried: a changed ETag fails the block with
+// a PreconditionFailedError.
+func (r *ObjectReader) fetch(b *readerBlock) {
+	defer close(b.done)
+
+	start := b.index * r.opts.BlockSize
+	end := start + r.opts.BlockSize
+	if end > r.meta.Size {
+		end = r.meta.Size
+	}
+	for attempt := 0; attempt <= r.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt*attempt) * 100 * time.Millisecond)
+		}
+		b.data, b.err = r.get(start, end)
+		if b.err == nil {
+			return
+		}
+		var e *httplib.ErrorResponse
+		if errors.As(b.err, &e) && e.StatusCode < 500 {
+			break
+		}
+	}
+	b.data = nil
+}
+
+func (r *ObjectReader) get(start, end int64) ([]byte, error) {
+	res, err := r.client.GetObjectWithOptions(r.bucketName, r.objectName, &GetObjectOptions{
+		ObjectConditions: ObjectConditions{IfMatch: r.meta.ETag},
+		Range:            &ObjectRange{Start: start, End: end - 1},
+		Encryption:       r.opts.Encryption,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data := make([]byte, end-start)
+	if _, err = io.ReadFull(res.Body, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,164 @@
+package bos
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestObjectReader(t *testing.T) {
+	f, c := newFakeBos(t)
+	content := randomContent(t, 10000)
+	f.putObject(TestBukketName, "movie.mp4", content, nil)
+
+	r, err := c.OpenObjectWithOptions(TestBukketName, "movie.mp4", &ObjectReaderOptions{BlockSize: 1000, ReadAhead: 2})
+	if err != nil {
+		t.Fatalf("OpenObjectWithOptions failed. %v", err)
+	}
+	if r.Size() != 10000 || r.Meta().ETag != f.object(TestBukketName, "movie.mp4").eTag {
+		t.Errorf("OpenObjectWithOptions returned %+v", r.Meta())
+	}
+
+	// A moov atom at the end is probed before the start is read.
+	if _, err = r.Seek(-8, io.SeekEnd); err != nil {
+		t.Fatalf("Seek failed. %v", err)
+	}
+	tail, err := ioutil.ReadAll(r)
+	if err != nil || !bytes.Equal(tail, content[9992:]) {
+		t.Errorf("read of the tail returned %v", err)
+	}
+	if gets := rangeGets(f); len(gets) != 1 || gets["bytes=9000-9999"] != 1 {
+		t.Errorf("read after Seek sent %v, want the last block alone", gets)
+	}
+
+	r.Seek(0, io.SeekStart)
+	data, err := ioutil.ReadAll(r)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Fatalf("ReadAll failed. %v", err)
+	}
+	for block, n := range rangeGets(f) {
+		if n != 1 {
+			t.Errorf("%s was requested %d times", block, n)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for j := 0; j < 20; j++ {
+				off := rnd.Int63n(10000)
+				buf := make([]byte, rnd.Intn(2500)+1)
+				n, err := r.ReadAt(buf, off)
+				want := content[off:]
+				if len(want) > len(buf) {
+					want = want[:len(buf)]
+				}
+				if !bytes.Equal(buf[:n], want) || (err != nil && (err != io.EOF || n == len(buf))) {
+					t.Errorf("ReadAt(%d bytes, %d) returned %d, %v", len(buf), off, n, err)
+				}
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	if err = r.Close(); err != nil {
+		t.Errorf("Close failed. %v", err)
+	}
+	if _, err = r.ReadAt(make([]byte, 10), 0); err == nil {
+		t.Errorf("ReadAt should fail after Close")
+	}
+}
+
+func TestObjectReaderReconnect(t *testing.T) {
+	f, c := newFakeBos(t)
+	content := randomContent(t, 3000)
+	f.putObject(TestBukketName, "movie.mp4", content, nil)
+
+	// The first request fails, and the second breaks off halfway through the block.
+	failures := 0
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodGet || r.Header.Get("Range") == "" || failures >= 2 {
+			return false
+		}
+		failures++
+		if failures == 1 {
+			f.fail(w, http.StatusServiceUnavailable, "ServiceUnavailable", "try again")
+			return true
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(1000))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(content[:500])
+		return true
+	}
+
+	r, err := c.OpenObjectWithOptions(TestBukketName, "movie.mp4",
+		&ObjectReaderOptions{BlockSize: 1000, ReadAhead: -1, MaxRetries: 2})
+	if err != nil {
+		t.Fatalf("OpenObjectWithOptions failed. %v", err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil || !bytes.Equal(data, content) || failures != 2 {
+		t.Fatalf("ReadAll did not reconnect. %v", err)
+	}
+}
+
+func TestObjectReaderDefaultRetries(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "movie.mp4", []byte("movie"), nil)
+
+	failures := 0
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodGet || failures >= 1 {
+			return false
+		}
+		failures++
+		f.fail(w, http.StatusServiceUnavailable, "ServiceUnavailable", "try again")
+		return true
+	}
+	r, err := c.OpenObject(TestBukketName, "movie.mp4")
+	if err != nil {
+		t.Fatalf("OpenObject failed. %v", err)
+	}
+	defer r.Close()
+	if data, err := ioutil.ReadAll(r); err != nil || string(data) != "movie" {
+		t.Errorf("OpenObject reader did not retry. %q, %v", data, err)
+	}
+
+	failures = 0
+	r, err = c.OpenObjectWithOptions(TestBukketName, "movie.mp4", &ObjectReaderOptions{MaxRetries: -1})
+	if err != nil {
+		t.Fatalf("OpenObjectWithOptions failed. %v", err)
+	}
+	defer r.Close()
+	if _, err = ioutil.ReadAll(r); err == nil {
+		t.Errorf("reader with retries disabled should fail")
+	}
+}
+
+func TestObjectReaderReplacedObject(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "movie.mp4", []byte("original"), nil)
+
+	r, err := c.OpenObject(TestBukketName, "movie.mp4")
+	if err != nil {
+		t.Fatalf("OpenObject failed. %v", err)
+	}
+	defer r.Close()
+	f.putObject(TestBukketName, "movie.mp4", []byte("replaced"), nil)
+	if _, err = ioutil.ReadAll(r); !IsPreconditionFailed(err) {
+		t.Errorf("reading a replaced object returned %v", err)
+	}
+
+	if _, err = c.OpenObject(TestBukketName, "missing.mp4"); err == nil {
+		t.Errorf("OpenObject of a missing object should fail")
+	}
+}
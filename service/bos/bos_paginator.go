@@ -0,0 +1,283 @@
+package bos
+
+import (
+	"context"
+)
+
+// The iterators below walk a listing across as many requests as it takes, following
+// the markers BOS returns until the listing is no longer truncated. They are used like
+// bufio.Scanner:
+//
+//	it := c.NewObjectIterator(ctx, bucketName, &ListObjectsArgs{Prefix: "hls/"}, 0)
+//	for it.Next() {
+//		obj := it.Object()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Cancelling the context stops the iteration before the next page is fetched, and a
+// positive maxItems stops it after that many items.
+
+// pager holds what the iterators have in common.
+type pager struct {
+	ctx      context.Context
+	maxItems int
+	count    int
+
+	// index is the position of the current item in the current page of pageLen items;
+	// more reports whether another page follows.
+	index   int
+	pageLen int
+	more    bool
+	started bool
+	done    bool
+	err     error
+}
+
+// advance moves to the next item, calling fetch for a new page whenever the current one
+// is used up. fetch returns the length of the new page and whether another one follows.
+func (p *pager) advance(fetch func() (int, bool, error)) bool {
+	if p.done || p.err != nil {
+		return false
+	}
+	if p.maxItems > 0 && p.count >= p.maxItems {
+		p.done = true
+		return false
+	}
+
+	p.index++
+	for !p.started || p.index >= p.pageLen {
+		if p.started && !p.more {
+			p.done = true
+			return false
+		}
+		if err := p.ctx.Err(); err != nil {
+			p.err = err
+			return false
+		}
+		pageLen, more, err := fetch()
+		if err != nil {
+			p.err = err
+			return false
+		}
+		p.started, p.index, p.pageLen, p.more = true, 0, pageLen, more
+	}
+	p.count++
+	return true
+}
+
+// ObjectIterator iterates over the objects of a bucket.
+type ObjectIterator struct {
+	pager
+	client     *BosClient
+	bucketName string
+	args       ListObjectsArgs
+	page       *ListObjectsResponse
+}
+
+// NewObjectIterator returns an iterator over the objects matching args, starting at
+// args.Marker. maxItems limits the number of objects returned, 0 meaning no limit.
+func (c *BosClient) NewObjectIterator(ctx context.Context, bucketName string, args *ListObjectsArgs,
+	maxItems int) *ObjectIterator {
+
+	it := &ObjectIterator{
+		pager:      pager{ctx: ctx, maxItems: maxItems},
+		client:     c,
+		bucketName: bucketName,
+		page:       &ListObjectsResponse{},
+	}
+	if args != nil {
+		it.args = *args
+	}
+	return it
+}
+
+// Next advances to the next object, and reports whether there is one.
+func (it *ObjectIterator) Next() bool {
+	return it.advance(func() (int, bool, error) {
+		if it.started {
+			it.args.Marker = nextObjectMarker(it.page)
+		}
+		page, err := it.client.ListObjectsWithArgs(it.bucketName, &it.args)
+		if err != nil {
+			return 0, false, err
+		}
+		it.page = page
+		marker := nextObjectMarker(page)
+		return len(page.Contents), page.IsTruncated && marker != "" && marker != it.args.Marker, nil
+	})
+}
+
+// nextObjectMarker returns the marker that continues a truncated listing.
+func nextObjectMarker(page *ListObjectsResponse) string {
+	if page.NextMarker != "" {
+		return page.NextMarker
+	}
+	// With a delimiter the page may end on a common prefix rather than an object.
+	marker := ""
+	if n := len(page.Contents); n > 0 {
+		marker = page.Contents[n-1].ObjectName
+	}
+	if n := len(page.CommonPrefixes); n > 0 && page.CommonPrefixes[n-1].Prefix > marker {
+		marker = page.CommonPrefixes[n-1].Prefix
+	}
+	return marker
+}
+
+// Object returns the current object.
+func (it *ObjectIterator) Object() ObjectInfo {
+	return it.page.Contents[it.index]
+}
+
+// Page returns the page holding the current object.
+func (it *ObjectIterator) Page() *ListObjectsResponse {
+	return it.page
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *ObjectIterator) Err() error {
+	return it.err
+}
+
+// PartIterator iterates over the parts of a multipart upload.
+type PartIterator struct {
+	pager
+	client     *BosClient
+	bucketName string
+	objectName string
+	uploadId   string
+	args       ListPartsArgs
+	page       *ListPartsResponse
+}
+
+// NewPartIterator returns an iterator over the parts uploaded so far, starting after
+// args.PartNumberMarker. maxItems limits the number of parts returned, 0 meaning no
+// limit.
+func (c *BosClient) NewPartIterator(ctx context.Context, bucketName, objectName, uploadId string,
+	args *ListPartsArgs, maxItems int) *PartIterator {
+
+	it := &PartIterator{
+		pager:      pager{ctx: ctx, maxItems: maxItems},
+		client:     c,
+		bucketName: bucketName,
+		objectName: objectName,
+		uploadId:   uploadId,
+		page:       &ListPartsResponse{},
+	}
+	if args != nil {
+		it.args = *args
+	}
+	return it
+}
+
+// Next advances to the next part, and reports whether there is one.
+func (it *PartIterator) Next() bool {
+	return it.advance(func() (int, bool, error) {
+		if it.started {
+			it.args.PartNumberMarker = nextPartMarker(it.page)
+		}
+		page, err := it.client.ListPartsWithArgs(it.bucketName, it.objectName, it.uploadId, &it.args)
+		if err != nil {
+			return 0, false, err
+		}
+		it.page = page
+		return len(page.Parts), page.IsTruncated && nextPartMarker(page) > it.args.PartNumberMarker, nil
+	})
+}
+
+// nextPartMarker returns the marker that continues a truncated listing.
+func nextPartMarker(page *ListPartsResponse) int {
+	if page.NextPartNumberMarker > 0 {
+		return page.NextPartNumberMarker
+	}
+	if n := len(page.Parts); n > 0 {
+		return page.Parts[n-1].PartNumber
+	}
+	return 0
+}
+
+// Part returns the current part.
+func (it *PartIterator) Part() PartInfo {
+	return it.page.Parts[it.index]
+}
+
+// Page returns the page holding the current part.
+func (it *PartIterator) Page() *ListPartsResponse {
+	return it.page
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *PartIterator) Err() error {
+	return it.err
+}
+
+// MultipartUploadIterator iterates over the multipart uploads in progress in a bucket.
+type MultipartUploadIterator struct {
+	pager
+	client     *BosClient
+	bucketName string
+	args       ListMultipartUploadsArgs
+	page       *ListMultipartUploadsResponse
+}
+
+// NewMultipartUploadIterator returns an iterator over the uploads matching args,
+// starting after args.KeyMarker. maxItems limits the number of uploads returned, 0
+// meaning no limit.
+func (c *BosClient) NewMultipartUploadIterator(ctx context.Context, bucketName string,
+	args *ListMultipartUploadsArgs, maxItems int) *MultipartUploadIterator {
+
+	it := &MultipartUploadIterator{
+		pager:      pager{ctx: ctx, maxItems: maxItems},
+		client:     c,
+		bucketName: bucketName,
+		page:       &ListMultipartUploadsResponse{},
+	}
+	if args != nil {
+		it.args = *args
+	}
+	return it
+}
+
+// Next advances to the next upload, and reports whether there is one.
+func (it *MultipartUploadIterator) Next() bool {
+	return it.advance(func() (int, bool, error) {
+		if it.started {
+			it.args.KeyMarker = nextUploadMarker(it.page)
+		}
+		page, err := it.client.ListMultipartUploadsWithArgs(it.bucketName, &it.args)
+		if err != nil {
+			return 0, false, err
+		}
+		it.page = page
+		marker := nextUploadMarker(page)
+		return len(page.Uploads), page.IsTruncated && marker != "" && marker != it.args.KeyMarker, nil
+	})
+}
+
+// nextUploadMarker returns the marker that continues a truncated listing.
+func nextUploadMarker(page *ListMultipartUploadsResponse) string {
+	if page.NextKeyMarker != "" {
+		return page.NextKeyMarker
+	}
+	if n := len(page.Uploads); n > 0 {
+		return page.Uploads[n-1].ObjectName
+	}
+	return ""
+}
+
+// Upload returns the current upload.
+func (it *MultipartUploadIterator) Upload() UploadInfo {
+	return it.page.Uploads[it.index]
+}
+
+// Page returns the page holding the current upload.
+func (it *MultipartUploadIterator) Page() *ListMultipartUploadsResponse {
+	return it.page
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *MultipartUploadIterator) Err() error {
+	return it.err
+}
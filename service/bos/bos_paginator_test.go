@@ -0,0 +1,187 @@
+package bos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// listRequests counts the ListObjects requests f has seen so far.
+func listRequests(f *fakeBos) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, r := range f.requests {
+		if r.Method == http.MethodGet && isListQuery(r.URL.Query()) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestObjectIterator(t *testing.T) {
+	f, c := newFakeBos(t)
+	for i := 0; i < 25; i++ {
+		f.putObject(TestBukketName, fmt.Sprintf("hls/seg-%03d.ts", i), []byte("ts"), nil)
+	}
+	f.putObject(TestBukketName, "other", []byte("x"), nil)
+
+	it := c.NewObjectIterator(context.Background(), TestBukketName, &ListObjectsArgs{Prefix: "hls/", MaxKeys: 10}, 0)
+	n := 0
+	for it.Next() {
+		if want := fmt.Sprintf("hls/seg-%03d.ts", n); it.Object().ObjectName != want {
+			t.Errorf("ObjectIterator got %q, want %q", it.Object().ObjectName, want)
+		}
+		n++
+	}
+	if it.Err() != nil || n != 25 {
+		t.Errorf("ObjectIterator returned %d objects, err %v", n, it.Err())
+	}
+	if got := listRequests(f); got != 3 {
+		t.Errorf("ObjectIterator sent %d list requests, want 3", got)
+	}
+
+	it = c.NewObjectIterator(context.Background(), TestBukketName, &ListObjectsArgs{Prefix: "hls/", MaxKeys: 10}, 12)
+	n = 0
+	for it.Next() {
+		n++
+	}
+	if n != 12 {
+		t.Errorf("ObjectIterator with maxItems returned %d objects, want 12", n)
+	}
+}
+
+func TestObjectIteratorCancel(t *testing.T) {
+	f, c := newFakeBos(t)
+	for i := 0; i < 25; i++ {
+		f.putObject(TestBukketName, fmt.Sprintf("seg-%03d.ts", i), []byte("ts"), nil)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it := c.NewObjectIterator(ctx, TestBukketName, &ListObjectsArgs{MaxKeys: 10}, 0)
+	n := 0
+	for it.Next() {
+		n++
+		cancel()
+	}
+	if it.Err() != context.Canceled || n != 10 {
+		t.Errorf("ObjectIterator returned %d objects, err %v; want 10, context.Canceled", n, it.Err())
+	}
+}
+
+func TestPartIterator(t *testing.T) {
+	_, c := newFakeBos(t)
+	res, err := c.InitiateMultipartUpload(TestBukketName, TestObjectName, "")
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload failed. %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		if _, err = c.UploadPartWithOptions(TestBukketName, TestObjectName, res.UploadId, i,
+			bytes.NewReader([]byte{byte(i)}), nil); err != nil {
+			t.Fatalf("UploadPartWithOptions failed. %v", err)
+		}
+	}
+
+	it := c.NewPartIterator(context.Background(), TestBukketName, TestObjectName, res.UploadId,
+		&ListPartsArgs{MaxParts: 2}, 0)
+	n := 0
+	for it.Next() {
+		n++
+		if it.Part().PartNumber != n {
+			t.Errorf("PartIterator got part %d, want %d", it.Part().PartNumber, n)
+		}
+	}
+	if it.Err() != nil || n != 5 {
+		t.Errorf("PartIterator returned %d parts, err %v", n, it.Err())
+	}
+}
+
+func TestMultipartUploadIterator(t *testing.T) {
+	_, c := newFakeBos(t)
+	for _, key := range []string{"a.mp4", "b.mp4", "c.mp4"} {
+		if _, err := c.InitiateMultipartUpload(TestBukketName, key, ""); err != nil {
+			t.Fatalf("InitiateMultipartUpload failed. %v", err)
+		}
+	}
+
+	it := c.NewMultipartUploadIterator(context.Background(), TestBukketName,
+		&ListMultipartUploadsArgs{MaxUploads: 1}, 0)
+	var keys []string
+	for it.Next() {
+		keys = append(keys, it.Upload().ObjectName)
+	}
+	if it.Err() != nil || fmt.Sprint(keys) != "[a.mp4 b.mp4 c.mp4]" {
+		t.Errorf("MultipartUploadIterator returned %v, err %v", keys, it.Err())
+	}
+}
+
+func TestListObjectsUntypedArgs(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "a", []byte("a"), nil)
+	f.putObject(TestBukketName, "b", []byte("b"), nil)
+
+	res, err := c.ListObjects(TestBukketName, nil, nil, 1, nil)
+	if err != nil || len(res.Contents) != 1 || !res.IsTruncated {
+		t.Errorf("ListObjects with an int maxKeys failed. %v", err)
+	}
+	if _, err = c.ListObjects(TestBukketName, nil, nil, 1.5, nil); err == nil {
+		t.Errorf("ListObjects should reject a non-integer maxKeys")
+	}
+}
+
+func TestListDirectory(t *testing.T) {
+	f, c := newFakeBos(t)
+	for _, key := range []string{
+		"movies/", "movies/a.mp4", "movies/b.mp4", "movies/hls/seg-0.ts", "movies/hls/seg-1.ts",
+		"movies/posters/a.jpg", "movies/z.mp4", "music/c.mp3",
+	} {
+		f.putObject(TestBukketName, key, []byte(key), nil)
+	}
+
+	for _, prefix := range []string{"movies", "/movies/"} {
+		dir, err := c.ListDirectory(TestBukketName, prefix)
+		if err != nil {
+			t.Fatalf("ListDirectory failed. %v", err)
+		}
+		if fmt.Sprint(dir.Prefixes) != "[movies/hls/ movies/posters/]" {
+			t.Errorf("ListDirectory prefixes = %v", dir.Prefixes)
+		}
+		var names []string
+		for _, obj := range dir.Objects {
+			names = append(names, obj.ObjectName)
+			if obj.LastModified.IsZero() || obj.StorageClass != StorageClassStandard {
+				t.Errorf("ListDirectory %s: LastModified %v, StorageClass %q", obj.ObjectName,
+					obj.LastModified, obj.StorageClass)
+			}
+		}
+		if fmt.Sprint(names) != "[movies/a.mp4 movies/b.mp4 movies/z.mp4]" {
+			t.Errorf("ListDirectory objects = %v", names)
+		}
+	}
+
+	dir, err := c.ListDirectory(TestBukketName, "")
+	if err != nil || fmt.Sprint(dir.Prefixes) != "[movies/ music/]" || len(dir.Objects) != 0 {
+		t.Errorf("ListDirectory of the bucket root = %+v, %v", dir, err)
+	}
+}
+
+func TestObjectIteratorDelimiter(t *testing.T) {
+	f, c := newFakeBos(t)
+	for _, key := range []string{"a/1", "a/2", "b", "c/1", "d"} {
+		f.putObject(TestBukketName, key, []byte(key), nil)
+	}
+	// Strip the server's nextMarker so the iterator has to work it out from the page.
+	f.omitNextMarker = true
+
+	it := c.NewObjectIterator(context.Background(), TestBukketName, &ListObjectsArgs{Delimiter: "/", MaxKeys: 1}, 0)
+	var names []string
+	for it.Next() {
+		names = append(names, it.Object().ObjectName)
+	}
+	if it.Err() != nil || fmt.Sprint(names) != "[b d]" {
+		t.Errorf("ObjectIterator with a delimiter returned %v, err %v", names, it.Err())
+	}
+}
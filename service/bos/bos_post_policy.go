@@ -0,0 +1,124 @@
+package bos
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+)
+
+/*
+ * Name: PostObject
+ * URL: http://bce.baidu.com/doc/BOS/API.html#PostObject.E6.8E.A5.E5.8F.A3
+ */
+
+const DefaultPostPolicyExpiration = time.Hour
+
+// Names of the fields of a PostObject form. The file itself must be the last field of
+// the form.
+const (
+	PostFormAccessKey             = "accessKey"
+	PostFormPolicy                = "policy"
+	PostFormSignature             = "signature"
+	PostFormKey                   = "key"
+	PostFormContentType           = "Content-Type"
+	PostFormSuccessActionRedirect = "success-action-redirect"
+	PostFormFile                  = "file"
+)
+
+// PostPolicy describes the uploads a browser may make by posting an HTML form straight
+// to BOS, without going through the application's servers.
+type PostPolicy struct {
+	BucketName string
+
+	// Key fixes the key of the object. Otherwise the page sets the key field itself,
+	// and it must start with KeyPrefix.
+	Key       string
+	KeyPrefix string
+
+	// MinContentLength and MaxContentLength bound the size of the file, when
+	// MaxContentLength is set.
+	MinContentLength int64
+	MaxContentLength int64
+
+	// ContentType fixes the type of the object. Otherwise the page may set it, and it
+	// must start with ContentTypePrefix, such as "video/", when that is set.
+	ContentType       string
+	ContentTypePrefix string
+
+	// Expiration is when the form stops being accepted; it defaults to
+	// DefaultPostPolicyExpiration from now.
+	Expiration time.Time
+
+	// SuccessActionRedirect is where the browser is sent after a successful upload.
+	SuccessActionRedirect string
+}
+
+// PostPolicyForm is what a page needs to upload with an HTML form: the form posts to
+// URL, with Fields as hidden inputs before the PostFormFile input.
+type PostPolicyForm struct {
+	URL        string
+	Fields     map[string]string
+	Expiration time.Time
+}
+
+// PresignPostPolicy builds and signs the policy document for p, and returns the form
+// fields carrying it.
+func (c *BosClient) PresignPostPolicy(p *PostPolicy) (*PostPolicyForm, error) {
+	if p == nil || p.BucketName == "" {
+		return nil, errors.New("a post policy needs a bucket")
+	}
+	if p.MinContentLength < 0 || p.MaxContentLength < 0 ||
+		(p.MaxContentLength > 0 && p.MinContentLength > p.MaxContentLength) {
+		return nil, errors.New("bad content length range in post policy")
+	}
+	expiration := p.Expiration
+	if expiration.IsZero() {
+		expiration = time.Now().Add(DefaultPostPolicyExpiration)
+	}
+	expiration = expiration.UTC().Truncate(time.Second)
+
+	fields := map[string]string{}
+	conditions := []interface{}{
+		map[string]string{"bucket": p.BucketName},
+	}
+	if p.Key != "" {
+		fields[PostFormKey] = c.formatPath(p.Key)
+		conditions = append(conditions, map[string]string{PostFormKey: fields[PostFormKey]})
+	} else {
+		conditions = append(conditions, []string{"starts-with", "$" + PostFormKey, c.formatPath(p.KeyPrefix)})
+	}
+	if p.MaxContentLength > 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", p.MinContentLength, p.MaxContentLength})
+	}
+	if p.ContentType != "" {
+		fields[PostFormContentType] = p.ContentType
+		conditions = append(conditions, map[string]string{PostFormContentType: p.ContentType})
+	} else if p.ContentTypePrefix != "" {
+		conditions = append(conditions, []string{"starts-with", "$" + PostFormContentType, p.ContentTypePrefix})
+	}
+	if p.SuccessActionRedirect != "" {
+		fields[PostFormSuccessActionRedirect] = p.SuccessActionRedirect
+		conditions = append(conditions, map[string]string{PostFormSuccessActionRedirect: p.SuccessActionRedirect})
+	}
+
+	document, err := json.Marshal(map[string]interface{}{
+		"expiration": expiration.Format(time.RFC3339),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	policy := base64.StdEncoding.EncodeToString(document)
+	fields[PostFormAccessKey] = c.Credential.AccessKeyId
+	fields[PostFormPolicy] = policy
+	fields[PostFormSignature] = auth.SignPostPolicyWithDebug(c.Credential, policy, c.Debug || auth.Debug)
+
+	return &PostPolicyForm{
+		URL:        c.GetBaseURL() + "/" + p.BucketName,
+		Fields:     fields,
+		Expiration: expiration,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package bos
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postForm posts an upload form the way a browser does, with the file last, and
+// returns the response without following redirects.
+func postForm(t *testing.T, url string, fields map[string]string, fileName string, data []byte) *http.Response {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		w.WriteField(name, value)
+	}
+	part, err := w.CreateFormFile(PostFormFile, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	w.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	res, err := client.Post(url, w.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("form post failed. %v", err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func TestPresignPostPolicy(t *testing.T) {
+	f, c := newFakeBos(t)
+
+	expiration := time.Now().Add(10 * time.Minute)
+	form, err := c.PresignPostPolicy(&PostPolicy{
+		BucketName:            TestBukketName,
+		KeyPrefix:             "uploads/user-7/",
+		MaxContentLength:      1024,
+		ContentTypePrefix:     "video/",
+		Expiration:            expiration,
+		SuccessActionRedirect: "https://example.com/done",
+	})
+	if err != nil {
+		t.Fatalf("PresignPostPolicy failed. %v", err)
+	}
+	if form.Fields[PostFormAccessKey] != fakeAccessKey || !form.Expiration.Equal(expiration.UTC().Truncate(time.Second)) ||
+		!regexp.MustCompile(`^[0-9a-f]{64}$`).MatchString(form.Fields[PostFormSignature]) {
+		t.Errorf("PresignPostPolicy returned %+v", form)
+	}
+	document, err := base64.StdEncoding.DecodeString(form.Fields[PostFormPolicy])
+	if err != nil {
+		t.Fatalf("policy is not base64. %v", err)
+	}
+	var policy struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}
+	if err = json.Unmarshal(document, &policy); err != nil || len(policy.Conditions) != 5 ||
+		policy.Expiration != form.Expiration.Format(time.RFC3339) {
+		t.Errorf("policy document is %s. %v", document, err)
+	}
+
+	fields := func(extra map[string]string) map[string]string {
+		all := map[string]string{}
+		for k, v := range form.Fields {
+			all[k] = v
+		}
+		for k, v := range extra {
+			all[k] = v
+		}
+		return all
+	}
+	res := postForm(t, form.URL, fields(map[string]string{
+		PostFormKey:         "uploads/user-7/clip.mp4",
+		PostFormContentType: "video/mp4",
+	}), "clip.mp4", []byte("clip"))
+	if res.StatusCode != http.StatusSeeOther || res.Header.Get("Location") != "https://example.com/done" {
+		t.Errorf("form upload returned %d, Location %q", res.StatusCode, res.Header.Get("Location"))
+	}
+	obj := f.object(TestBukketName, "uploads/user-7/clip.mp4")
+	if obj == nil || string(obj.data) != "clip" || obj.header.Get("Content-Type") != "video/mp4" {
+		t.Fatalf("form upload did not store the object")
+	}
+
+	for name, extra := range map[string]map[string]string{
+		"key outside the prefix": {PostFormKey: "uploads/user-8/clip.mp4", PostFormContentType: "video/mp4"},
+		"wrong content type":     {PostFormKey: "uploads/user-7/page.html", PostFormContentType: "text/html"},
+		"changed policy":         {PostFormKey: "uploads/user-7/a.mp4", PostFormContentType: "video/mp4", PostFormPolicy: base64.StdEncoding.EncodeToString([]byte(`{"conditions":[]}`))},
+	} {
+		if res = postForm(t, form.URL, fields(extra), "file", []byte("data")); res.StatusCode != http.StatusForbidden {
+			t.Errorf("form upload with %s returned %d", name, res.StatusCode)
+		}
+	}
+	res = postForm(t, form.URL, fields(map[string]string{PostFormKey: "uploads/user-7/big.mp4", PostFormContentType: "video/mp4"}),
+		"big.mp4", []byte(strings.Repeat("x", 1025)))
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("form upload of a file too large returned %d", res.StatusCode)
+	}
+}
+
+func TestPresignPostPolicyFixedKey(t *testing.T) {
+	f, c := newFakeBos(t)
+
+	form, err := c.PresignPostPolicy(&PostPolicy{
+		BucketName:  TestBukketName,
+		Key:         "/avatars/7.jpg",
+		ContentType: "image/jpeg",
+		Expiration:  time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("PresignPostPolicy failed. %v", err)
+	}
+	if form.Fields[PostFormKey] != "avatars/7.jpg" || form.Fields[PostFormContentType] != "image/jpeg" {
+		t.Errorf("PresignPostPolicy returned fields %v", form.Fields)
+	}
+	if res := postForm(t, form.URL, form.Fields, "7.jpg", []byte("jpeg")); res.StatusCode != http.StatusForbidden {
+		t.Errorf("form upload with an expired policy returned %d", res.StatusCode)
+	}
+
+	form, _ = c.PresignPostPolicy(&PostPolicy{BucketName: TestBukketName, Key: "avatars/7.jpg", ContentType: "image/jpeg"})
+	if res := postForm(t, form.URL, form.Fields, "7.jpg", []byte("jpeg")); res.StatusCode != http.StatusNoContent {
+		t.Errorf("form upload returned %d", res.StatusCode)
+	}
+	if obj := f.object(TestBukketName, "avatars/7.jpg"); obj == nil || string(obj.data) != "jpeg" {
+		t.Errorf("form upload did not store the object")
+	}
+
+	for _, p := range []*PostPolicy{
+		nil,
+		{Key: "no-bucket"},
+		{BucketName: TestBukketName, MinContentLength: 10, MaxContentLength: 5},
+	} {
+		if _, err = c.PresignPostPolicy(p); err == nil {
+			t.Errorf("PresignPostPolicy accepted %+v", p)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package bos
+
+import (
+	"fmt"
+)
+
+// RefererPolicy is the anti-leech setting of a bucket: which pages may embed or link
+// to its objects, such as player pages and thumbnails. Entries are Referer patterns in
+// which * is a wildcard, such as "http://*.example.com/*".
+//
+// With a Whitelist, anonymous reads are only allowed from matching pages. With only a
+// Blacklist, anonymous reads are allowed from any page but the matching ones. Reads
+// signed with the bucket owner's credentials are never restricted.
+type RefererPolicy struct {
+	Whitelist []string
+	Blacklist []string
+}
+
+// SetBucketRefererPolicy replaces the grants of a bucket's ACL to GranteeAllUsers with
+// ones carrying out policy, keeping the other grants. An empty policy makes the bucket
+// private to anonymous users.
+func (c *BosClient) SetBucketRefererPolicy(bucketName string, policy *RefererPolicy) error {
+	if policy == nil {
+		policy = &RefererPolicy{}
+	}
+	for _, pattern := range append(append([]string{}, policy.Whitelist...), policy.Blacklist...) {
+		if pattern == "" {
+			return fmt.Errorf("referer pattern must not be empty")
+		}
+	}
+	acl, err := c.GetBucketAcl(bucketName)
+	if err != nil {
+		return err
+	}
+
+	grants := []GranteeGroup{}
+	for _, g := range acl.AccessControlList {
+		if !grantsAllUsers(g) {
+			grants = append(grants, g)
+			continue
+		}
+		others := []GranteeInfo{}
+		for _, grantee := range g.Grantee {
+			if grantee.Id != GranteeAllUsers {
+				others = append(others, grantee)
+			}
+		}
+		if len(others) > 0 {
+			g.Grantee = others
+			grants = append(grants, g)
+		}
+	}
+	allUsers := []GranteeInfo{{Id: GranteeAllUsers}}
+	if len(policy.Whitelist) > 0 {
+		grants = append(grants, GranteeGroup{
+			Grantee:    allUsers,
+			Permission: []string{PermissionRead},
+			Condition:  &AclCondition{Referer: &RefererCondition{StringLike: policy.Whitelist}},
+		})
+	}
+	if len(policy.Blacklist) > 0 {
+		if len(policy.Whitelist) == 0 {
+			grants = append(grants, GranteeGroup{Grantee: allUsers, Permission: []string{PermissionRead}})
+		}
+		grants = append(grants, GranteeGroup{
+			Grantee:    allUsers,
+			Permission: []string{PermissionRead},
+			Condition:  &AclCondition{Referer: &RefererCondition{StringLike: policy.Blacklist}},
+			Effect:     AclEffectDeny,
+		})
+	}
+	return c.SetBucketAclWithGrants(bucketName, grants)
+}
+
+// GetBucketRefererPolicy reads the referer policy back from a bucket's ACL. A bucket
+// without referer conditions has an empty policy, whether or not it is public.
+func (c *BosClient) GetBucketRefererPolicy(bucketName string) (*RefererPolicy, error) {
+	acl, err := c.GetBucketAcl(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	policy := &RefererPolicy{}
+	for _, g := range acl.AccessControlList {
+		if !grantsAllUsers(g) || g.Condition == nil || g.Condition.Referer == nil {
+			continue
+		}
+		patterns := append(append([]string{}, g.Condition.Referer.StringLike...), g.Condition.Referer.StringEquals...)
+		if g.Effect == AclEffectDeny {
+			policy.Blacklist = append(policy.Blacklist, patterns...)
+		} else {
+			policy.Whitelist = append(policy.Whitelist, patterns...)
+		}
+	}
+	return policy, nil
+}
+
+func grantsAllUsers(g GranteeGroup) bool {
+	for _, grantee := range g.Grantee {
+		if grantee.Id == GranteeAllUsers {
+			return true
+		}
+	}
+	return false
+}
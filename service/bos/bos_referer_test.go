@@ -0,0 +1,52 @@
+package bos
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBucketRefererPolicy(t *testing.T) {
+	_, c := newFakeBos(t)
+	if err := c.SetBucketAcl(TestBukketName, CannedAclPublicRead); err != nil {
+		t.Fatalf("SetBucketAcl failed. %v", err)
+	}
+	if policy, err := c.GetBucketRefererPolicy(TestBukketName); err != nil || len(policy.Whitelist)+len(policy.Blacklist) != 0 {
+		t.Errorf("GetBucketRefererPolicy of a public bucket returned %+v, %v", policy, err)
+	}
+
+	whitelist := &RefererPolicy{Whitelist: []string{"http://*.example.com/*", "https://player.example.net/*"}}
+	if err := c.SetBucketRefererPolicy(TestBukketName, whitelist); err != nil {
+		t.Fatalf("SetBucketRefererPolicy failed. %v", err)
+	}
+	acl, _ := c.GetBucketAcl(TestBukketName)
+	if len(acl.AccessControlList) != 2 || acl.AccessControlList[0].Grantee[0].Id != "owner-id" ||
+		acl.AccessControlList[1].Condition == nil {
+		t.Errorf("whitelist produced ACL %+v", acl.AccessControlList)
+	}
+	if policy, err := c.GetBucketRefererPolicy(TestBukketName); err != nil || !reflect.DeepEqual(policy, whitelist) {
+		t.Errorf("GetBucketRefererPolicy returned %+v, %v", policy, err)
+	}
+
+	blacklist := &RefererPolicy{Blacklist: []string{"http://*.leech.example/*"}}
+	if err := c.SetBucketRefererPolicy(TestBukketName, blacklist); err != nil {
+		t.Fatalf("SetBucketRefererPolicy failed. %v", err)
+	}
+	acl, _ = c.GetBucketAcl(TestBukketName)
+	if n := len(acl.AccessControlList); n != 3 || acl.AccessControlList[1].Condition != nil ||
+		acl.AccessControlList[2].Effect != AclEffectDeny {
+		t.Errorf("blacklist produced ACL %+v", acl.AccessControlList)
+	}
+	if policy, err := c.GetBucketRefererPolicy(TestBukketName); err != nil || !reflect.DeepEqual(policy, blacklist) {
+		t.Errorf("GetBucketRefererPolicy returned %+v, %v", policy, err)
+	}
+
+	if err := c.SetBucketRefererPolicy(TestBukketName, nil); err != nil {
+		t.Fatalf("SetBucketRefererPolicy failed. %v", err)
+	}
+	if acl, _ = c.GetBucketAcl(TestBukketName); len(acl.AccessControlList) != 1 {
+		t.Errorf("empty policy left ACL %+v", acl.AccessControlList)
+	}
+	if err := c.SetBucketRefererPolicy(TestBukketName, &RefererPolicy{Whitelist: []string{""}}); err == nil {
+		t.Errorf("SetBucketRefererPolicy accepted an empty pattern")
+	}
+}
@@ -0,0 +1,225 @@
+package bos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+const (
+	ReplicationStatusEnabled  = "enabled"
+	ReplicationStatusDisabled = "disabled"
+)
+
+// Replication states of an object, as reported in ObjectMeta.ReplicationStatus.
+// ObjectReplicationReplica marks the copy in the destination bucket.
+const (
+	ObjectReplicationPending   = "PENDING"
+	ObjectReplicationCompleted = "COMPLETED"
+	ObjectReplicationFailed    = "FAILED"
+	ObjectReplicationReplica   = "REPLICA"
+)
+
+// ReplicationRule copies the objects of a bucket matching Resource to a bucket in
+// another region as they are written, such as a media bucket in bj mirrored to gz for
+// disaster recovery.
+type ReplicationRule struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+
+	// Resource lists the objects the rule covers, as "<bucket>/<prefix>*" patterns;
+	// see LifecycleResource.
+	Resource    []string               `json:"resource"`
+	Destination ReplicationDestination `json:"destination"`
+
+	// ReplicateHistory, when set, also copies the objects written before the rule was
+	// put.
+	ReplicateHistory *ReplicationHistory `json:"replicateHistory,omitempty"`
+
+	// ReplicateDeletes is ReplicationStatusEnabled to delete the replica of an object
+	// when the object is deleted. Replicas are kept by default.
+	ReplicateDeletes string `json:"replicateDeletes,omitempty"`
+}
+
+type ReplicationDestination struct {
+	Bucket string `json:"bucket"`
+
+	// Region is the region of Bucket, such as "gz". It must differ from the region of
+	// the source bucket.
+	Region string `json:"region,omitempty"`
+
+	// StorageClass is the class of the replicas; they keep the class of the source
+	// objects when it is empty.
+	StorageClass string `json:"storageClass,omitempty"`
+}
+
+type ReplicationHistory struct {
+	StorageClass string `json:"storageClass,omitempty"`
+}
+
+// BucketReplicationProgress is how far a replication rule has got.
+type BucketReplicationProgress struct {
+	Status string `json:"status"`
+
+	// HistoryReplicationPercent is the share, from 0 to 100, of the objects written
+	// before the rule that have been copied, when the rule replicates history.
+	HistoryReplicationPercent float64 `json:"historyReplicationPercent"`
+
+	// LatestReplicationTime is when the last object was copied.
+	LatestReplicationTime time.Time `json:"latestReplicationTime"`
+}
+
+// ValidateReplicationRule checks rule for mistakes BOS would reject: a missing ID or
+// status, resources outside bucketName, a destination that is the bucket itself, and
+// unknown storage classes.
+func ValidateReplicationRule(bucketName string, rule *ReplicationRule) error {
+	if rule == nil || rule.Id == "" {
+		return fmt.Errorf("replication rule needs an ID")
+	}
+	if rule.Status != ReplicationStatusEnabled && rule.Status != ReplicationStatusDisabled {
+		return fmt.Errorf("replication rule %q has bad status %q", rule.Id, rule.Status)
+	}
+	if len(rule.Resource) == 0 {
+		return fmt.Errorf("replication rule %q covers no objects", rule.Id)
+	}
+	for _, res := range rule.Resource {
+		if !strings.HasPrefix(res, bucketName+"/") {
+			return fmt.Errorf("replication rule %q covers %q, outside bucket %s", rule.Id, res, bucketName)
+		}
+	}
+	if rule.Destination.Bucket == "" || rule.Destination.Bucket == bucketName {
+		return fmt.Errorf("replication rule %q needs a destination bucket other than %s", rule.Id, bucketName)
+	}
+	classes := []string{rule.Destination.StorageClass}
+	if rule.ReplicateHistory != nil {
+		classes = append(classes, rule.ReplicateHistory.StorageClass)
+	}
+	for _, class := range classes {
+		if _, ok := storageClassRank[class]; class != "" && !ok {
+			return fmt.Errorf("replication rule %q has unknown storage class %q", rule.Id, class)
+		}
+	}
+	switch rule.ReplicateDeletes {
+	case "", ReplicationStatusEnabled, ReplicationStatusDisabled:
+	default:
+		return fmt.Errorf("replication rule %q has bad replicateDeletes %q", rule.Id, rule.ReplicateDeletes)
+	}
+	return nil
+}
+
+func replicationQuery(resource, ruleId string) string {
+	return resource + "&id=" + url.QueryEscape(ruleId)
+}
+
+/*
+ * Name: PutBucketReplication
+ * URL: http://bce.baidu.com/doc/BOS/API.html#PutBucketReplication.E6.8E.A5.E5.8F.A3
+ */
+
+// PutBucketReplication sets the replication rule of a bucket with the ID of rule,
+// after checking it with ValidateReplicationRule.
+func (c *BosClient) PutBucketReplication(bucketName string, rule *ReplicationRule) (err error) {
+	if err = ValidateReplicationRule(bucketName, rule); err != nil {
+		return
+	}
+	jstring, err := json.Marshal(rule)
+	if err != nil {
+		return
+	}
+
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{},
+		Query:   replicationQuery("replication", rule.Id),
+		Path:    c.APIVersion + "/" + bucketName,
+		Body:    bytes.NewReader(jstring),
+		Type:    httplib.JSON,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+/*
+ * Name: GetBucketReplication
+ * URL: http://bce.baidu.com/doc/BOS/API.html#GetBucketReplication.E6.8E.A5.E5.8F.A3
+ */
+
+func (c *BosClient) GetBucketReplication(bucketName, ruleId string) (output *ReplicationRule, err error) {
+	req := &httplib.Request{
+		Method:  httplib.GET,
+		Headers: map[string]string{},
+		Query:   replicationQuery("replication", ruleId),
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ReplicationRule
+
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	return &response, nil
+}
+
+/*
+ * Name: DeleteBucketReplication
+ * URL: http://bce.baidu.com/doc/BOS/API.html#DeleteBucketReplication.E6.8E.A5.E5.8F.A3
+ */
+
+// DeleteBucketReplication stops a replication rule. The replicas made so far are kept.
+func (c *BosClient) DeleteBucketReplication(bucketName, ruleId string) (err error) {
+	req := &httplib.Request{
+		Method:  httplib.DELETE,
+		Headers: map[string]string{},
+		Query:   replicationQuery("replication", ruleId),
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+/*
+ * Name: GetBucketReplicationProgress
+ * URL: http://bce.baidu.com/doc/BOS/API.html#GetBucketReplicationProgress.E6.8E.A5.E5.8F.A3
+ */
+
+func (c *BosClient) GetBucketReplicationProgress(bucketName, ruleId string) (output *BucketReplicationProgress, err error) {
+	req := &httplib.Request{
+		Method:  httplib.GET,
+		Headers: map[string]string{},
+		Query:   replicationQuery("replicationProgress", ruleId),
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response BucketReplicationProgress
+
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	return &response, nil
+}
@@ -0,0 +1,93 @@
+package bos
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+)
+
+func TestBucketReplication(t *testing.T) {
+	f, c := newFakeBos(t)
+
+	rule := &ReplicationRule{
+		Id:               "dr-gz",
+		Status:           ReplicationStatusEnabled,
+		Resource:         []string{LifecycleResource(TestBukketName, "media/")},
+		Destination:      ReplicationDestination{Bucket: "media-gz", Region: "gz", StorageClass: StorageClassStandardIA},
+		ReplicateHistory: &ReplicationHistory{StorageClass: StorageClassCold},
+		ReplicateDeletes: ReplicationStatusEnabled,
+	}
+	if _, err := c.GetBucketReplication(TestBukketName, rule.Id); err == nil {
+		t.Errorf("GetBucketReplication should fail before the rule is put")
+	}
+	if err := c.PutBucketReplication(TestBukketName, rule); err != nil {
+		t.Fatalf("PutBucketReplication failed. %v", err)
+	}
+	if got, err := c.GetBucketReplication(TestBukketName, rule.Id); err != nil || !reflect.DeepEqual(got, rule) {
+		t.Errorf("GetBucketReplication returned %+v, %v", got, err)
+	}
+	if f.lastRequest(http.MethodGet).URL.Query().Get("id") != rule.Id {
+		t.Errorf("GetBucketReplication sent query %q", f.lastRequest(http.MethodGet).URL.RawQuery)
+	}
+
+	progress, err := c.GetBucketReplicationProgress(TestBukketName, rule.Id)
+	if err != nil || progress.Status != ReplicationStatusEnabled || progress.HistoryReplicationPercent != 100 ||
+		time.Since(progress.LatestReplicationTime) > time.Minute {
+		t.Errorf("GetBucketReplicationProgress returned %+v, %v", progress, err)
+	}
+
+	if err = c.DeleteBucketReplication(TestBukketName, rule.Id); err != nil {
+		t.Errorf("DeleteBucketReplication failed. %v", err)
+	}
+	if _, err = c.GetBucketReplicationProgress(TestBukketName, rule.Id); err == nil {
+		t.Errorf("GetBucketReplicationProgress should fail after the rule is deleted")
+	}
+}
+
+func TestValidateReplicationRule(t *testing.T) {
+	valid := func() *ReplicationRule {
+		return &ReplicationRule{
+			Id:          "dr",
+			Status:      ReplicationStatusEnabled,
+			Resource:    []string{TestBukketName + "/*"},
+			Destination: ReplicationDestination{Bucket: "backup"},
+		}
+	}
+	if err := ValidateReplicationRule(TestBukketName, valid()); err != nil {
+		t.Errorf("ValidateReplicationRule rejected a valid rule. %v", err)
+	}
+	for name, change := range map[string]func(*ReplicationRule){
+		"no ID":              func(r *ReplicationRule) { r.Id = "" },
+		"bad status":         func(r *ReplicationRule) { r.Status = "on" },
+		"no resource":        func(r *ReplicationRule) { r.Resource = nil },
+		"foreign resource":   func(r *ReplicationRule) { r.Resource = []string{"other/*"} },
+		"no destination":     func(r *ReplicationRule) { r.Destination.Bucket = "" },
+		"itself":             func(r *ReplicationRule) { r.Destination.Bucket = TestBukketName },
+		"bad storage class":  func(r *ReplicationRule) { r.Destination.StorageClass = "GLACIER" },
+		"bad history class":  func(r *ReplicationRule) { r.ReplicateHistory = &ReplicationHistory{StorageClass: "GLACIER"} },
+		"bad delete setting": func(r *ReplicationRule) { r.ReplicateDeletes = "yes" },
+	} {
+		rule := valid()
+		change(rule)
+		if err := ValidateReplicationRule(TestBukketName, rule); err == nil {
+			t.Errorf("ValidateReplicationRule accepted a rule with %s", name)
+		}
+	}
+}
+
+func TestObjectReplicationStatus(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "media/a.mp4", []byte("a"), http.Header{auth.BCE_REPLICATION_STATUS: {ObjectReplicationCompleted}})
+	f.putObject(TestBukketName, "local/b.mp4", []byte("b"), nil)
+
+	meta, err := c.GetObjectMeta(TestBukketName, "media/a.mp4")
+	if err != nil || meta.ReplicationStatus != ObjectReplicationCompleted {
+		t.Errorf("GetObjectMeta returned %+v, %v", meta, err)
+	}
+	if meta, err = c.GetObjectMeta(TestBukketName, "local/b.mp4"); err != nil || meta.ReplicationStatus != "" {
+		t.Errorf("GetObjectMeta of an object not replicated returned %+v, %v", meta, err)
+	}
+}
@@ -0,0 +1,83 @@
+package bos
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+)
+
+// Server-side encryption algorithms.
+const (
+	SSEAlgorithmAES256 = "AES256"
+	SSEAlgorithmSM4    = "SM4"
+	SSEAlgorithmKMS    = "KMS"
+)
+
+// ServerSideEncryption asks BOS to encrypt an object at rest. Set Algorithm to have BOS
+// manage the key, adding KmsKeyId with SSEAlgorithmKMS to pick a key held in KMS. Set
+// CustomerKey instead to encrypt with a 256-bit key of your own, which BOS does not
+// keep: the same key must then be given to read the object, copy it, or upload more
+// parts to it.
+type ServerSideEncryption struct {
+	Algorithm   string
+	KmsKeyId    string
+	CustomerKey []byte
+}
+
+func (sse *ServerSideEncryption) setHeaders(headers map[string]string) {
+	if sse == nil {
+		return
+	}
+	if sse.Algorithm != "" {
+		headers[auth.BCE_SERVER_SIDE_ENCRYPTION] = sse.Algorithm
+	}
+	if sse.KmsKeyId != "" {
+		headers[auth.BCE_SERVER_SIDE_ENCRYPTION_KMS_KEY_ID] = sse.KmsKeyId
+	}
+	sse.setCustomerKeyHeaders(headers, auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY,
+		auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY_MD5)
+}
+
+// setCopySourceHeaders adds the headers that unlock a copy source encrypted with a
+// customer key.
+func (sse *ServerSideEncryption) setCopySourceHeaders(headers map[string]string) {
+	if sse == nil {
+		return
+	}
+	sse.setCustomerKeyHeaders(headers, auth.BCE_COPY_SOURCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY,
+		auth.BCE_COPY_SOURCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY_MD5)
+}
+
+func (sse *ServerSideEncryption) setCustomerKeyHeaders(headers map[string]string, keyHeader, md5Header string) {
+	if len(sse.CustomerKey) == 0 {
+		return
+	}
+	sum := md5.Sum(sse.CustomerKey)
+	headers[keyHeader] = base64.StdEncoding.EncodeToString(sse.CustomerKey)
+	headers[md5Header] = base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// customerKeyOnly returns the part of sse that requests other than the one creating the
+// object repeat, such as UploadPart or GetObject, or nil when there is none.
+func (sse *ServerSideEncryption) customerKeyOnly() *ServerSideEncryption {
+	if sse == nil || len(sse.CustomerKey) == 0 {
+		return nil
+	}
+	return &ServerSideEncryption{CustomerKey: sse.CustomerKey}
+}
+
+// verifiableETag reports whether BOS still answers uploads with the MD5 of the data
+// sent as ETag. That is only certain for objects it does not encrypt, or encrypts with
+// a key of its own.
+func (sse *ServerSideEncryption) verifiableETag() bool {
+	return sse == nil || (sse.KmsKeyId == "" && sse.Algorithm != SSEAlgorithmKMS && len(sse.CustomerKey) == 0)
+}
+
+// encryptionState reads the server-side encryption of an object from the headers of a
+// response about it.
+func encryptionState(header http.Header) (algorithm, kmsKeyId, customerKeyMD5 string) {
+	return header.Get(auth.BCE_SERVER_SIDE_ENCRYPTION), header.Get(auth.BCE_SERVER_SIDE_ENCRYPTION_KMS_KEY_ID),
+		header.Get(auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY_MD5)
+}
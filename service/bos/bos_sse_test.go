@@ -0,0 +1,125 @@
+package bos
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+)
+
+func TestPutObjectServerSideEncryption(t *testing.T) {
+	f, c := newFakeBos(t)
+
+	_, err := c.PutObjectWithOptions(TestBukketName, "aes.mp4", bytes.NewReader([]byte("aes")),
+		&PutObjectOptions{Encryption: &ServerSideEncryption{Algorithm: SSEAlgorithmAES256}})
+	if err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+	meta, err := c.GetObjectMeta(TestBukketName, "aes.mp4")
+	if err != nil || meta.ServerSideEncryption != SSEAlgorithmAES256 {
+		t.Errorf("GetObjectMeta did not report the encryption. %v %v", err, meta)
+	}
+
+	_, err = c.PutObjectWithOptions(TestBukketName, "kms.mp4", bytes.NewReader([]byte("kms")),
+		&PutObjectOptions{Encryption: &ServerSideEncryption{Algorithm: SSEAlgorithmKMS, KmsKeyId: "key-1"}})
+	if err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+	meta, err = c.GetObjectMeta(TestBukketName, "kms.mp4")
+	if err != nil || meta.ServerSideEncryption != SSEAlgorithmKMS || meta.KmsKeyId != "key-1" {
+		t.Errorf("GetObjectMeta did not report the KMS key. %v %v", err, meta)
+	}
+
+	if h := f.lastRequest("PUT").Header.Get(auth.BCE_SERVER_SIDE_ENCRYPTION_KMS_KEY_ID); h != "key-1" {
+		t.Errorf("PutObjectWithOptions sent KMS key id %q", h)
+	}
+	meta, _ = c.GetObjectMeta(TestBukketName, "aes.mp4")
+	if meta.KmsKeyId != "" {
+		t.Errorf("GetObjectMeta reported a KMS key for an AES256 object. %v", meta)
+	}
+}
+
+func TestCustomerKeyEncryption(t *testing.T) {
+	f, c := newFakeBos(t)
+	key := bytes.Repeat([]byte{7}, 32)
+	sse := &ServerSideEncryption{CustomerKey: key}
+
+	if _, err := c.PutObjectWithOptions(TestBukketName, "secret.mp4", bytes.NewReader([]byte("secret")),
+		&PutObjectOptions{Encryption: sse}); err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+	req := f.lastRequest("PUT")
+	sum := md5.Sum(key)
+	if req.Header.Get(auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY) != base64.StdEncoding.EncodeToString(key) ||
+		req.Header.Get(auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY_MD5) != base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Errorf("PutObjectWithOptions sent bad customer key headers. %v", req.Header)
+	}
+
+	if _, err := c.GetObjectWithOptions(TestBukketName, "secret.mp4", nil); err == nil {
+		t.Errorf("GetObjectWithOptions should fail without the customer key")
+	}
+	res, err := c.GetObjectWithOptions(TestBukketName, "secret.mp4", &GetObjectOptions{Encryption: sse})
+	if err != nil {
+		t.Fatalf("GetObjectWithOptions failed. %v", err)
+	}
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(data) != "secret" {
+		t.Errorf("GetObjectWithOptions failed. Content Not Match.")
+	}
+
+	if _, err = c.CopyObjectWithOptions(TestBukketName, "secret.mp4", TestBukketName, "copy.mp4", nil); err == nil {
+		t.Errorf("CopyObjectWithOptions should fail without the source customer key")
+	}
+	_, err = c.CopyObjectWithOptions(TestBukketName, "secret.mp4", TestBukketName, "copy.mp4",
+		&CopyObjectOptions{SourceEncryption: sse, Meta: &PutObjectOptions{
+			Encryption: &ServerSideEncryption{Algorithm: SSEAlgorithmAES256},
+		}})
+	if err != nil {
+		t.Fatalf("CopyObjectWithOptions failed. %v", err)
+	}
+	if meta, err := c.GetObjectMeta(TestBukketName, "copy.mp4"); err != nil || meta.ServerSideEncryption != SSEAlgorithmAES256 {
+		t.Errorf("CopyObjectWithOptions did not re-encrypt the copy. %v %v", err, meta)
+	}
+}
+
+func TestUploaderCustomerKey(t *testing.T) {
+	f, c := newFakeBos(t)
+	key := bytes.Repeat([]byte{9}, 32)
+	sse := &ServerSideEncryption{CustomerKey: key}
+	content := randomContent(t, 2*MinPartSize+100)
+
+	u := NewUploader(c)
+	u.PartSize = MinPartSize
+	if _, err := u.Upload(TestBukketName, "master.mov", bytes.NewReader(content),
+		&PutObjectOptions{Encryption: sse}); err != nil {
+		t.Fatalf("Upload failed. %v", err)
+	}
+	if n := len(partUploads(f)); n != 3 {
+		t.Errorf("Upload sent %d parts, want 3", n)
+	}
+
+	u.singleCopyLimit = MinPartSize
+	res, err := u.CopyLargeObject(TestBukketName, "master.mov", TestBukketName, "archive.mov",
+		&CopyObjectOptions{SourceEncryption: sse, Meta: &PutObjectOptions{Encryption: sse}})
+	if err != nil || res.UploadId == "" {
+		t.Fatalf("CopyLargeObject failed. %v %+v", err, res)
+	}
+	obj := f.object(TestBukketName, "archive.mov")
+	if !bytes.Equal(obj.data, content) || obj.header.Get(auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY_MD5) == "" {
+		t.Errorf("CopyLargeObject did not keep the customer key encryption. %v", obj.header)
+	}
+
+	f.mu.Lock()
+	for _, r := range f.requests {
+		if r.Method == http.MethodPut && r.URL.Query().Get("uploadId") != "" &&
+			r.Header.Get(auth.BCE_SERVER_SIDE_ENCRYPTION_CUSTOMER_KEY) == "" {
+			t.Errorf("part %s sent without the customer key", r.URL.Query().Get("partNumber"))
+		}
+	}
+	f.mu.Unlock()
+}
@@ -0,0 +1,131 @@
+package bos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+// SetObjectStorageClass moves an object to another storage class by copying it onto
+// itself, keeping its headers and user metadata. Objects larger than
+// MaxSingleCopySize must be moved with Uploader.CopyLargeObject instead, and ARCHIVE
+// objects must be restored before they can be moved.
+func (c *BosClient) SetObjectStorageClass(bucketName, objectName, storageClass string) error {
+	_, err := c.CopyObjectWithOptions(bucketName, objectName, bucketName, objectName, &CopyObjectOptions{
+		MetadataDirective: MetadataDirectiveCopy,
+		Meta:              &PutObjectOptions{StorageClass: storageClass},
+	})
+	return err
+}
+
+// Restore tiers, from fastest to cheapest.
+const (
+	RestoreTierExpedited = "Expedited"
+	RestoreTierStandard  = "Standard"
+	RestoreTierLowCost   = "LowCost"
+)
+
+// Limits on how long a restored copy of an ARCHIVE object is kept.
+const (
+	MinRestoreDays = 1
+	MaxRestoreDays = 30
+)
+
+const DefaultRestorePollInterval = time.Minute
+
+// RestoreStatus is the state of the restore of an ARCHIVE object.
+type RestoreStatus struct {
+	// Ongoing is true until the restored copy can be read.
+	Ongoing bool
+
+	// ExpiryDate is when the restored copy goes away again, once the restore is done.
+	ExpiryDate time.Time
+}
+
+var (
+	restoreOngoingPattern = regexp.MustCompile(`ongoing-request="(true|false)"`)
+	restoreExpiryPattern  = regexp.MustCompile(`expiry-date="([^"]*)"`)
+)
+
+// parseRestoreStatus reads an x-bce-restore header, such as
+// `ongoing-request="false", expiry-date="Wed, 07 Nov 2029 00:00:00 GMT"`.
+func parseRestoreStatus(v string) *RestoreStatus {
+	m := restoreOngoingPattern.FindStringSubmatch(v)
+	if m == nil {
+		return nil
+	}
+	status := &RestoreStatus{Ongoing: m[1] == "true"}
+	if m = restoreExpiryPattern.FindStringSubmatch(v); m != nil {
+		status.ExpiryDate, _ = http.ParseTime(m[1])
+	}
+	return status
+}
+
+/*
+ * Name: RestoreObject
+ * URL: http://bce.baidu.com/doc/BOS/API.html#RestoreObject.E6.8E.A5.E5.8F.A3
+ */
+
+// RestoreObject starts making a readable copy of an ARCHIVE object, kept for days days.
+// An empty tier means RestoreTierStandard. The restore takes from minutes to hours
+// depending on the tier; WaitForRestore waits for it to finish.
+func (c *BosClient) RestoreObject(bucketName, objectName string, days int, tier string) (err error) {
+	if days < MinRestoreDays || days > MaxRestoreDays {
+		return fmt.Errorf("restore days must be between %d and %d, not %d", MinRestoreDays, MaxRestoreDays, days)
+	}
+	switch tier {
+	case "":
+		tier = RestoreTierStandard
+	case RestoreTierExpedited, RestoreTierStandard, RestoreTierLowCost:
+	default:
+		return fmt.Errorf("unknown restore tier %q", tier)
+	}
+
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method:  httplib.POST,
+		Headers: map[string]string{},
+		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
+		Query:   "restore",
+	}
+	req.Headers[auth.BCE_RESTORE_DAYS] = strconv.Itoa(days)
+	req.Headers[auth.BCE_RESTORE_TIER] = tier
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+// WaitForRestore polls the metadata of an object every interval, DefaultRestorePollInterval
+// when zero, until its restore is done, and returns the metadata then. It fails at once
+// for an object with no restore.
+func (c *BosClient) WaitForRestore(ctx context.Context, bucketName, objectName string,
+	interval time.Duration) (*ObjectMeta, error) {
+
+	if interval <= 0 {
+		interval = DefaultRestorePollInterval
+	}
+	for {
+		meta, err := c.GetObjectMeta(bucketName, objectName)
+		if err != nil {
+			return nil, err
+		}
+		if meta.Restore == nil {
+			return nil, fmt.Errorf("%s/%s is not being restored", bucketName, objectName)
+		}
+		if !meta.Restore.Ongoing {
+			return meta, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
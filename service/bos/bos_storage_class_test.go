@@ -0,0 +1,97 @@
+package bos
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/auth"
+)
+
+func TestSetObjectStorageClass(t *testing.T) {
+	f, c := newFakeBos(t)
+	_, err := c.PutObjectWithOptions(TestBukketName, "master.mov", bytes.NewReader([]byte("master")),
+		&PutObjectOptions{UserMeta: map[string]string{"title": "master"}})
+	if err != nil {
+		t.Fatalf("PutObjectWithOptions failed. %v", err)
+	}
+
+	if err = c.SetObjectStorageClass(TestBukketName, "master.mov", StorageClassCold); err != nil {
+		t.Fatalf("SetObjectStorageClass failed. %v", err)
+	}
+	meta, err := c.GetObjectMeta(TestBukketName, "master.mov")
+	if err != nil || meta.StorageClass != StorageClassCold || meta.UserMeta["title"] != "master" {
+		t.Errorf("SetObjectStorageClass left %v %+v", err, meta)
+	}
+	if obj := f.object(TestBukketName, "master.mov"); string(obj.data) != "master" {
+		t.Errorf("SetObjectStorageClass changed the content to %q", obj.data)
+	}
+
+	list, err := c.ListObjects(TestBukketName, nil, nil, nil, "master")
+	if err != nil || len(list.Contents) != 1 || list.Contents[0].StorageClass != StorageClassCold {
+		t.Errorf("ListObjects did not report the storage class. %v %+v", err, list)
+	}
+}
+
+func TestRestoreObject(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "archive.mov", []byte("archived"), http.Header{
+		"X-Bce-Storage-Class": {StorageClassArchive},
+	})
+	f.putObject(TestBukketName, "hot.mov", []byte("hot"), nil)
+
+	if _, err := c.GetObjectWithOptions(TestBukketName, "archive.mov", nil); err == nil {
+		t.Errorf("GetObjectWithOptions should fail on an archived object")
+	}
+	for _, days := range []int{0, 31} {
+		if err := c.RestoreObject(TestBukketName, "archive.mov", days, ""); err == nil {
+			t.Errorf("RestoreObject accepted %d days", days)
+		}
+	}
+	if err := c.RestoreObject(TestBukketName, "archive.mov", 3, "Instant"); err == nil {
+		t.Errorf("RestoreObject accepted an unknown tier")
+	}
+	if err := c.RestoreObject(TestBukketName, "hot.mov", 3, ""); err == nil {
+		t.Errorf("RestoreObject should fail on an object that is not archived")
+	}
+	if _, err := c.WaitForRestore(context.Background(), TestBukketName, "archive.mov", time.Millisecond); err == nil {
+		t.Errorf("WaitForRestore should fail before RestoreObject")
+	}
+
+	if err := c.RestoreObject(TestBukketName, "archive.mov", 3, RestoreTierExpedited); err != nil {
+		t.Fatalf("RestoreObject failed. %v", err)
+	}
+	req := f.lastRequest("POST")
+	if req.Header.Get(auth.BCE_RESTORE_DAYS) != "3" || req.Header.Get(auth.BCE_RESTORE_TIER) != RestoreTierExpedited {
+		t.Errorf("RestoreObject sent %v", req.Header)
+	}
+	if err := c.RestoreObject(TestBukketName, "archive.mov", 3, ""); err == nil {
+		t.Errorf("RestoreObject should fail while a restore is ongoing")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.WaitForRestore(ctx, TestBukketName, "archive.mov", 10*time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("WaitForRestore returned %v, want the context's deadline", err)
+	}
+
+	expiry := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+	time.AfterFunc(30*time.Millisecond, func() { f.finishRestore(TestBukketName, "archive.mov", expiry) })
+	meta, err := c.WaitForRestore(context.Background(), TestBukketName, "archive.mov", 10*time.Millisecond)
+	if err != nil || meta.Restore == nil || meta.Restore.Ongoing || !meta.Restore.ExpiryDate.Equal(expiry) {
+		t.Fatalf("WaitForRestore failed. %v %+v", err, meta)
+	}
+
+	res, err := c.GetObjectWithOptions(TestBukketName, "archive.mov", nil)
+	if err != nil {
+		t.Fatalf("GetObjectWithOptions of the restored object failed. %v", err)
+	}
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(data) != "archived" || res.StorageClass != StorageClassArchive {
+		t.Errorf("GetObjectWithOptions returned %q, %+v", data, res.ObjectMeta)
+	}
+}
@@ -0,0 +1,490 @@
+package bos
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sync directions.
+const (
+	// SyncUpload makes the BOS prefix a copy of the local directory.
+	SyncUpload = "upload"
+
+	// SyncDownload makes the local directory a copy of the BOS prefix.
+	SyncDownload = "download"
+)
+
+// Actions reported for each file in a SyncReport.
+const (
+	SyncActionUpload   = "upload"
+	SyncActionDownload = "download"
+	SyncActionDelete   = "delete"
+	SyncActionSkip     = "skip"
+)
+
+const DefaultSyncConcurrency = 4
+
+// SyncOptions tunes Sync. The zero value uploads new and changed files,
+// DefaultSyncConcurrency at a time, and deletes nothing.
+type SyncOptions struct {
+	// Direction is SyncUpload, the default, or SyncDownload.
+	Direction string
+
+	// Delete removes the files of the destination that are not in the source. Files left
+	// out by Include and Exclude are never deleted.
+	Delete bool
+
+	// DryRun reports what would be done without transferring or deleting anything.
+	DryRun bool
+
+	// Include and Exclude are path.Match patterns. A pattern holding a "/" is matched
+	// against the whole path relative to the directory, such as "720p/*.ts"; any other
+	// against the file name alone, such as "*.m3u8". When Include is set only matching
+	// files are synced, and Exclude wins over Include.
+	Include []string
+	Exclude []string
+
+	// Concurrency is the number of files transferred at the same time.
+	Concurrency int
+
+	// Uploader and Downloader, when set, transfer the files, so that their part sizes
+	// and retries can be tuned. Files larger than a part are sent as multipart uploads.
+	Uploader   *Uploader
+	Downloader *Downloader
+
+	// Progress, when set, is called as each file is done. Calls are never concurrent.
+	Progress func(SyncItem)
+}
+
+// SyncItem is what Sync did, or would do in a dry run, with one file.
+type SyncItem struct {
+	Key  string
+	Path string
+
+	// Action is one of the SyncAction constants, and Reason says why it was taken, such
+	// as "new", "size differs" or "unchanged".
+	Action string
+	Reason string
+	Size   int64
+	Err    error
+}
+
+// SyncReport sums up a Sync. In a dry run the counts are those of the actions that
+// would have been taken.
+type SyncReport struct {
+	Direction string
+	DryRun    bool
+	Started   time.Time
+	Finished  time.Time
+
+	// Items holds every file synced, skipped as unchanged or deleted, sorted by key.
+	Items []SyncItem
+
+	Uploaded         int
+	Downloaded       int
+	Deleted          int
+	Skipped          int
+	Failed           int
+	BytesTransferred int64
+}
+
+// Sync mirrors the local directory localDir and the objects under prefix, such as the
+// output folder of a render and its HLS/DASH copy in BOS. Files missing from the
+// destination, or differing from the source in size, are transferred. Files of the same
+// size are left alone when the destination is not older than the source, and otherwise
+// compared by MD5 against the ETag.
+//
+// The report is always returned. err is set when the directory or the prefix cannot be
+// listed, or when any file failed; failed files carry their own error in the report.
+func (c *BosClient) Sync(ctx context.Context, localDir, bucketName, prefix string, opts *SyncOptions) (*SyncReport, error) {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	direction := opts.Direction
+	if direction == "" {
+		direction = SyncUpload
+	}
+	if direction != SyncUpload && direction != SyncDownload {
+		return nil, fmt.Errorf("unknown sync direction %q", direction)
+	}
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad sync pattern %q: %v", pattern, err)
+		}
+	}
+
+	s := &syncer{
+		client:     c,
+		opts:       opts,
+		direction:  direction,
+		localDir:   localDir,
+		bucketName: bucketName,
+		uploader:   opts.Uploader,
+		downloader: opts.Downloader,
+		report:     &SyncReport{Direction: direction, DryRun: opts.DryRun, Started: time.Now()},
+	}
+	if prefix = strings.Trim(c.formatPath(prefix), "/"); prefix != "" {
+		s.prefix = prefix + "/"
+	}
+	if s.uploader == nil {
+		s.uploader = NewUploader(c)
+	}
+	if s.downloader == nil {
+		s.downloader = NewDownloader(c)
+	}
+
+	err := s.run(ctx)
+	s.report.Finished = time.Now()
+	sort.Slice(s.report.Items, func(i, j int) bool { return s.report.Items[i].Key < s.report.Items[j].Key })
+	if err == nil && s.report.Failed > 0 {
+		err = fmt.Errorf("sync of %s with %s/%s: %d files failed, the first with: %v",
+			localDir, bucketName, s.prefix, s.report.Failed, s.firstErr)
+	}
+	return s.report, err
+}
+
+// syncEntry is a path found on either side of a sync.
+type syncEntry struct {
+	rel    string
+	local  os.FileInfo
+	remote *ObjectInfo
+}
+
+type syncer struct {
+	client     *BosClient
+	opts       *SyncOptions
+	direction  string
+	localDir   string
+	bucketName string
+	prefix     string
+	uploader   *Uploader
+	downloader *Downloader
+
+	mu       sync.Mutex
+	report   *SyncReport
+	firstErr error
+}
+
+func (s *syncer) run(ctx context.Context) error {
+	entries, err := s.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	var extras []*syncEntry
+	concurrency := s.opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSyncConcurrency
+	}
+	var wg sync.WaitGroup
+	jobs := make(chan *syncEntry)
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				s.record(s.sync(ctx, e))
+			}
+		}()
+	}
+	for _, e := range entries {
+		if s.source(e) {
+			jobs <- e
+		} else if s.opts.Delete {
+			extras = append(extras, e)
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(extras) > 0 {
+		s.delete(ctx, extras)
+	}
+	return nil
+}
+
+// list pairs the files of the directory with the objects under the prefix, leaving out
+// those that do not pass the filters.
+func (s *syncer) list(ctx context.Context) ([]*syncEntry, error) {
+	byPath := map[string]*syncEntry{}
+	entry := func(rel string) *syncEntry {
+		e := byPath[rel]
+		if e == nil {
+			e = &syncEntry{rel: rel}
+			byPath[rel] = e
+		}
+		return e
+	}
+
+	_, err := os.Stat(s.localDir)
+	if err == nil {
+		err = filepath.Walk(s.localDir, func(p string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(s.localDir, p)
+			if err != nil {
+				return err
+			}
+			if rel = filepath.ToSlash(rel); s.opts.match(rel) {
+				entry(rel).local = info
+			}
+			return nil
+		})
+	} else if os.IsNotExist(err) && s.direction == SyncDownload {
+		// The directory is created by the first download.
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	it := s.client.NewObjectIterator(ctx, s.bucketName, &ListObjectsArgs{Prefix: s.prefix}, 0)
+	for it.Next() {
+		obj := it.Object()
+		rel := strings.TrimPrefix(obj.ObjectName, s.prefix)
+		// Keys ending with a slash are directory placeholders.
+		if rel == "" || strings.HasSuffix(rel, "/") || !s.opts.match(rel) {
+			continue
+		}
+		entry(rel).remote = &obj
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*syncEntry, 0, len(byPath))
+	for _, e := range byPath {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].rel < entries[j].rel })
+	return entries, nil
+}
+
+func (o *SyncOptions) match(rel string) bool {
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			name := rel
+			if !strings.Contains(pattern, "/") {
+				name = path.Base(rel)
+			}
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if len(o.Include) > 0 && !matches(o.Include) {
+		return false
+	}
+	return !matches(o.Exclude)
+}
+
+// source reports whether e exists on the side being copied from.
+func (s *syncer) source(e *syncEntry) bool {
+	if s.direction == SyncUpload {
+		return e.local != nil
+	}
+	return e.remote != nil
+}
+
+func (s *syncer) key(e *syncEntry) string {
+	return s.prefix + e.rel
+}
+
+func (s *syncer) localPath(e *syncEntry) string {
+	return filepath.Join(s.localDir, filepath.FromSlash(e.rel))
+}
+
+// safePath reports whether the key of e maps to a file inside the directory. Keys such
+// as "a/../../b" are never written or deleted locally.
+func safePath(rel string) bool {
+	return path.Clean(rel) == rel && !path.IsAbs(rel) && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// sync transfers e from its source to its destination, unless they are the same.
+func (s *syncer) sync(ctx context.Context, e *syncEntry) SyncItem {
+	item := SyncItem{Key: s.key(e), Path: s.localPath(e), Action: SyncActionUpload}
+	if s.direction == SyncUpload {
+		item.Size = e.local.Size()
+	} else {
+		item.Action, item.Size = SyncActionDownload, e.remote.Size
+		if !safePath(e.rel) {
+			item.Action, item.Reason = SyncActionSkip, "key escapes the directory"
+			return item
+		}
+	}
+
+	if item.Reason, item.Err = s.compare(e); item.Err != nil {
+		return item
+	}
+	if item.Reason == "" {
+		item.Action, item.Reason = SyncActionSkip, "unchanged"
+		return item
+	}
+	if s.opts.DryRun {
+		return item
+	}
+	if item.Err = ctx.Err(); item.Err != nil {
+		return item
+	}
+
+	if s.direction == SyncUpload {
+		item.Err = s.upload(ctx, item.Key, item.Path)
+	} else {
+		item.Err = s.download(item.Key, item.Path, e.remote.LastModified)
+	}
+	return item
+}
+
+var md5ETagPattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// compare returns why e must be transferred, or "" when its destination is up to date.
+func (s *syncer) compare(e *syncEntry) (string, error) {
+	if e.local == nil || e.remote == nil {
+		return "new", nil
+	}
+	if e.local.Size() != e.remote.Size {
+		return "size differs", nil
+	}
+	mtime, lastModified := e.local.ModTime(), e.remote.LastModified
+	if (s.direction == SyncUpload && !lastModified.Before(mtime)) ||
+		(s.direction == SyncDownload && !mtime.Before(lastModified)) {
+		return "", nil
+	}
+	// The ETag of a multipart upload is not the MD5 of the content.
+	if !md5ETagPattern.MatchString(e.remote.ETag) {
+		return "modified", nil
+	}
+	sum, err := fileMD5(s.localPath(e))
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(sum, e.remote.ETag) {
+		return "", nil
+	}
+	return "content differs", nil
+}
+
+func fileMD5(fileName string) (string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := md5.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func (s *syncer) upload(ctx context.Context, key, fileName string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = s.uploader.upload(ctx, s.bucketName, key, file, nil)
+	return err
+}
+
+// download fetches key into fileName and gives the file the object's modification time,
+// so that the next sync finds it up to date without reading it.
+func (s *syncer) download(key, fileName string, lastModified time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	if _, err := s.downloader.DownloadFile(s.bucketName, key, fileName); err != nil {
+		return err
+	}
+	return os.Chtimes(fileName, lastModified, lastModified)
+}
+
+// delete removes the files of the destination that are not in the source.
+func (s *syncer) delete(ctx context.Context, extras []*syncEntry) {
+	items := make([]SyncItem, len(extras))
+	for i, e := range extras {
+		items[i] = SyncItem{Key: s.key(e), Path: s.localPath(e), Action: SyncActionDelete, Reason: "not in source"}
+		if s.direction == SyncUpload {
+			items[i].Size = e.remote.Size
+		} else {
+			items[i].Size = e.local.Size()
+		}
+	}
+
+	switch {
+	case s.opts.DryRun:
+	case ctx.Err() != nil:
+		for i := range items {
+			items[i].Err = ctx.Err()
+		}
+	case s.direction == SyncUpload:
+		keys := make([]string, len(items))
+		for i := range items {
+			keys[i] = items[i].Key
+		}
+		res, err := s.client.DeleteMultipleObjects(s.bucketName, keys)
+		failed := map[string]error{}
+		if err == nil {
+			for i := range res.Errors {
+				failed[res.Errors[i].ObjectName] = &res.Errors[i]
+			}
+		}
+		for i := range items {
+			if err != nil {
+				items[i].Err = err
+			} else {
+				items[i].Err = failed[items[i].Key]
+			}
+		}
+	default:
+		for i := range items {
+			items[i].Err = os.Remove(items[i].Path)
+		}
+	}
+
+	for _, item := range items {
+		s.record(item)
+	}
+}
+
+func (s *syncer) record(item SyncItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.report
+	r.Items = append(r.Items, item)
+	switch {
+	case item.Err != nil:
+		r.Failed++
+		if s.firstErr == nil {
+			s.firstErr = fmt.Errorf("%s %s: %v", item.Action, item.Key, item.Err)
+		}
+	case item.Action == SyncActionUpload:
+		r.Uploaded++
+		r.BytesTransferred += item.Size
+	case item.Action == SyncActionDownload:
+		r.Downloaded++
+		r.BytesTransferred += item.Size
+	case item.Action == SyncActionDelete:
+		r.Deleted++
+	case item.Action == SyncActionSkip:
+		r.Skipped++
+	}
+	if s.opts.Progress != nil {
+		s.opts.Progress(item)
+	}
+}
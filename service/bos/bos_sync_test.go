@@ -0,0 +1,166 @@
+package bos
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeSyncFile(t *testing.T, dir, rel string, data []byte) string {
+	name := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func syncActions(report *SyncReport) map[string]string {
+	actions := map[string]string{}
+	for _, item := range report.Items {
+		actions[item.Key] = item.Action
+	}
+	return actions
+}
+
+func TestSyncUpload(t *testing.T) {
+	f, c := newFakeBos(t)
+	dir := t.TempDir()
+	writeSyncFile(t, dir, "master.m3u8", []byte("#EXTM3U"))
+	writeSyncFile(t, dir, "720p/seg-001.ts", []byte("segment one"))
+	writeSyncFile(t, dir, "720p/seg-002.ts", []byte("segment two"))
+	writeSyncFile(t, dir, "render.log", []byte("log"))
+	f.putObject(TestBukketName, "show/old.ts", []byte("old"), nil)
+	f.putObject(TestBukketName, "show/keep.log", []byte("kept"), nil)
+
+	opts := &SyncOptions{Exclude: []string{"*.log"}}
+	report, err := c.Sync(context.Background(), dir, TestBukketName, "/show/", opts)
+	if err != nil {
+		t.Fatalf("Sync failed. %v", err)
+	}
+	if report.Uploaded != 3 || report.Deleted != 0 || report.BytesTransferred != 29 || len(report.Items) != 3 {
+		t.Errorf("Sync reported %+v", report)
+	}
+	if obj := f.object(TestBukketName, "show/720p/seg-001.ts"); obj == nil || string(obj.data) != "segment one" {
+		t.Errorf("Sync did not upload 720p/seg-001.ts")
+	}
+	if f.object(TestBukketName, "show/render.log") != nil {
+		t.Errorf("Sync uploaded an excluded file")
+	}
+
+	// Nothing changed: everything is skipped.
+	report, err = c.Sync(context.Background(), dir, TestBukketName, "show", opts)
+	if err != nil || report.Skipped != 3 || report.Uploaded != 0 {
+		t.Errorf("second Sync reported %v %+v", err, report)
+	}
+
+	// A file changed without changing size is found by its MD5.
+	name := writeSyncFile(t, dir, "720p/seg-002.ts", []byte("segment 2!!"))
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(name, later, later)
+	opts.Delete = true
+	report, err = c.Sync(context.Background(), dir, TestBukketName, "show", opts)
+	if err != nil {
+		t.Fatalf("third Sync failed. %v", err)
+	}
+	actions := syncActions(report)
+	if actions["show/720p/seg-002.ts"] != SyncActionUpload || actions["show/old.ts"] != SyncActionDelete ||
+		actions["show/master.m3u8"] != SyncActionSkip || report.Uploaded != 1 || report.Deleted != 1 {
+		t.Errorf("third Sync reported %+v", report)
+	}
+	if string(f.object(TestBukketName, "show/720p/seg-002.ts").data) != "segment 2!!" {
+		t.Errorf("Sync did not upload the changed file")
+	}
+	if f.object(TestBukketName, "show/old.ts") != nil || f.object(TestBukketName, "show/keep.log") == nil {
+		t.Errorf("Sync deleted the wrong objects")
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	f, c := newFakeBos(t)
+	dir := t.TempDir()
+	writeSyncFile(t, dir, "a.ts", []byte("a"))
+	writeSyncFile(t, dir, "sub/b.ts", []byte("b"))
+	writeSyncFile(t, dir, "sub/c.mp4", []byte("c"))
+	f.putObject(TestBukketName, "out/extra.ts", []byte("extra"), nil)
+
+	var progress []string
+	report, err := c.Sync(context.Background(), dir, TestBukketName, "out", &SyncOptions{
+		DryRun:   true,
+		Delete:   true,
+		Include:  []string{"sub/*", "*.ts"},
+		Exclude:  []string{"*.mp4"},
+		Progress: func(item SyncItem) { progress = append(progress, item.Key) },
+	})
+	if err != nil {
+		t.Fatalf("Sync failed. %v", err)
+	}
+	actions := syncActions(report)
+	if len(actions) != 3 || actions["out/a.ts"] != SyncActionUpload || actions["out/sub/b.ts"] != SyncActionUpload ||
+		actions["out/extra.ts"] != SyncActionDelete || len(progress) != 3 || !report.DryRun {
+		t.Errorf("Sync reported %+v", report)
+	}
+	if f.object(TestBukketName, "out/a.ts") != nil || f.object(TestBukketName, "out/extra.ts") == nil {
+		t.Errorf("a dry run changed the bucket")
+	}
+
+	if _, err = c.Sync(context.Background(), dir, TestBukketName, "out", &SyncOptions{Include: []string{"["}}); err == nil {
+		t.Errorf("Sync accepted a bad pattern")
+	}
+}
+
+func TestSyncDownload(t *testing.T) {
+	f, c := newFakeBos(t)
+	dir := filepath.Join(t.TempDir(), "mirror")
+	f.putObject(TestBukketName, "vod/index.mpd", []byte("<MPD/>"), nil)
+	f.putObject(TestBukketName, "vod/video/init.m4s", []byte("init"), nil)
+	f.putObject(TestBukketName, "vod/video/", nil, nil)
+	f.putObject(TestBukketName, "vod/../escape", []byte("x"), nil)
+	f.putObject(TestBukketName, "other/file", []byte("other"), nil)
+
+	opts := &SyncOptions{Direction: SyncDownload, Delete: true}
+	report, err := c.Sync(context.Background(), dir, TestBukketName, "vod", opts)
+	if err != nil {
+		t.Fatalf("Sync failed. %v", err)
+	}
+	actions := syncActions(report)
+	if report.Downloaded != 2 || actions["vod/../escape"] != SyncActionSkip {
+		t.Errorf("Sync reported %+v", report)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "video", "init.m4s"))
+	if err != nil || !bytes.Equal(data, []byte("init")) {
+		t.Errorf("Sync did not download video/init.m4s. %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "..", "escape")); err == nil {
+		t.Errorf("Sync wrote outside the directory")
+	}
+
+	// Downloaded files carry the object's time, so a second run downloads nothing and
+	// removes local extras.
+	writeSyncFile(t, dir, "stale.m4s", []byte("stale"))
+	report, err = c.Sync(context.Background(), dir, TestBukketName, "vod", opts)
+	actions = syncActions(report)
+	if err != nil || report.Downloaded != 0 || actions["vod/stale.m4s"] != SyncActionDelete ||
+		actions["vod/index.mpd"] != SyncActionSkip {
+		t.Errorf("second Sync reported %v %+v", err, report)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "stale.m4s")); !os.IsNotExist(err) {
+		t.Errorf("Sync did not remove the local extra")
+	}
+
+	// An object rewritten with the same size is downloaded again.
+	f.putObject(TestBukketName, "vod/index.mpd", []byte("<mpd/>"), nil).lastModified = time.Now().Add(time.Hour).UTC()
+	report, err = c.Sync(context.Background(), dir, TestBukketName, "vod", opts)
+	if err != nil || report.Downloaded != 1 {
+		t.Errorf("third Sync reported %v %+v", err, report)
+	}
+	if data, _ = ioutil.ReadFile(filepath.Join(dir, "index.mpd")); string(data) != "<mpd/>" {
+		t.Errorf("Sync left index.mpd as %q", data)
+	}
+}
@@ -0,0 +1,177 @@
+package bos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+// Limits on the tags of an object.
+const (
+	MaxObjectTags     = 10
+	MaxTagKeyLength   = 128
+	MaxTagValueLength = 256
+)
+
+type ObjectTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ObjectTagSet is the set of tags of an object, which lifecycle rules and access
+// policies can select objects by, such as a tag marking the renditions of a title.
+type ObjectTagSet []ObjectTag
+
+// NewObjectTagSet returns the tag set holding tags, sorted by key.
+func NewObjectTagSet(tags map[string]string) ObjectTagSet {
+	set := make(ObjectTagSet, 0, len(tags))
+	for k, v := range tags {
+		set = append(set, ObjectTag{Key: k, Value: v})
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i].Key < set[j].Key })
+	return set
+}
+
+// Get returns the value of the tag called key.
+func (s ObjectTagSet) Get(key string) (string, bool) {
+	for _, t := range s {
+		if t.Key == key {
+			return t.Value, true
+		}
+	}
+	return "", false
+}
+
+// Map returns the tags keyed by name.
+func (s ObjectTagSet) Map() map[string]string {
+	m := make(map[string]string, len(s))
+	for _, t := range s {
+		m[t.Key] = t.Value
+	}
+	return m
+}
+
+// Validate checks the set for mistakes BOS would reject: too many tags, empty,
+// duplicate or overlong keys, and overlong values.
+func (s ObjectTagSet) Validate() error {
+	if len(s) == 0 || len(s) > MaxObjectTags {
+		return fmt.Errorf("an object needs 1 to %d tags, not %d", MaxObjectTags, len(s))
+	}
+	keys := map[string]bool{}
+	for _, t := range s {
+		if t.Key == "" || len(t.Key) > MaxTagKeyLength {
+			return fmt.Errorf("tag key %q must be 1 to %d bytes long", t.Key, MaxTagKeyLength)
+		}
+		if len(t.Value) > MaxTagValueLength {
+			return fmt.Errorf("value of tag %q is longer than %d bytes", t.Key, MaxTagValueLength)
+		}
+		if keys[t.Key] {
+			return fmt.Errorf("duplicate tag key %q", t.Key)
+		}
+		keys[t.Key] = true
+	}
+	return nil
+}
+
+// objectTagging is the document of the ?tagging sub-resource.
+type objectTagging struct {
+	TagSet []objectTagInfo `json:"tagSet"`
+}
+
+type objectTagInfo struct {
+	TagInfo ObjectTagSet `json:"tagInfo"`
+}
+
+/*
+ * Name: PutObjectTagging
+ * URL: http://bce.baidu.com/doc/BOS/API.html#PutObjectTagging.E6.8E.A5.E5.8F.A3
+ */
+
+// PutObjectTagging replaces the tags of an object, after checking them with
+// ObjectTagSet.Validate.
+func (c *BosClient) PutObjectTagging(bucketName, objectName string, tags ObjectTagSet) (err error) {
+	if err = tags.Validate(); err != nil {
+		return
+	}
+	jstring, err := json.Marshal(objectTagging{TagSet: []objectTagInfo{{TagInfo: tags}}})
+	if err != nil {
+		return
+	}
+
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{},
+		Query:   "tagging",
+		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
+		Body:    bytes.NewReader(jstring),
+		Type:    httplib.JSON,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+/*
+ * Name: GetObjectTagging
+ * URL: http://bce.baidu.com/doc/BOS/API.html#GetObjectTagging.E6.8E.A5.E5.8F.A3
+ */
+
+// GetObjectTagging returns the tags of an object. An object without tags has an empty
+// set.
+func (c *BosClient) GetObjectTagging(bucketName, objectName string) (ObjectTagSet, error) {
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method:  httplib.GET,
+		Headers: map[string]string{},
+		Query:   "tagging",
+		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
+	}
+
+	res, err := c.DoRequest(req)
+	if e, ok := err.(*httplib.ErrorResponse); ok && e.Code == "NoSuchTagSet" {
+		return ObjectTagSet{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc objectTagging
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	if err = j.Decode(&doc); err != nil {
+		return nil, err
+	}
+	tags := ObjectTagSet{}
+	for _, s := range doc.TagSet {
+		tags = append(tags, s.TagInfo...)
+	}
+	return tags, nil
+}
+
+/*
+ * Name: DeleteObjectTagging
+ * URL: http://bce.baidu.com/doc/BOS/API.html#DeleteObjectTagging.E6.8E.A5.E5.8F.A3
+ */
+
+func (c *BosClient) DeleteObjectTagging(bucketName, objectName string) (err error) {
+	objectName = c.formatPath(objectName)
+	req := &httplib.Request{
+		Method:  httplib.DELETE,
+		Headers: map[string]string{},
+		Query:   "tagging",
+		Path:    c.APIVersion + "/" + bucketName + "/" + objectName,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
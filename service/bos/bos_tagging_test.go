@@ -0,0 +1,63 @@
+package bos
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObjectTagging(t *testing.T) {
+	f, c := newFakeBos(t)
+	f.putObject(TestBukketName, "renditions/720p.mp4", []byte("720p"), nil)
+
+	tags, err := c.GetObjectTagging(TestBukketName, "renditions/720p.mp4")
+	if err != nil || len(tags) != 0 {
+		t.Errorf("GetObjectTagging of an untagged object returned %v, %v", tags, err)
+	}
+
+	want := NewObjectTagSet(map[string]string{"title": "t-1042", "tier": "archive-after-90d"})
+	if err = c.PutObjectTagging(TestBukketName, "/renditions/720p.mp4", want); err != nil {
+		t.Fatalf("PutObjectTagging failed. %v", err)
+	}
+	tags, err = c.GetObjectTagging(TestBukketName, "renditions/720p.mp4")
+	if err != nil || !reflect.DeepEqual(tags, want) {
+		t.Errorf("GetObjectTagging returned %v, %v", tags, err)
+	}
+	if v, ok := tags.Get("title"); !ok || v != "t-1042" || tags.Map()["tier"] != "archive-after-90d" {
+		t.Errorf("tag set lookups failed on %v", tags)
+	}
+
+	if err = c.DeleteObjectTagging(TestBukketName, "renditions/720p.mp4"); err != nil {
+		t.Fatalf("DeleteObjectTagging failed. %v", err)
+	}
+	if tags, err = c.GetObjectTagging(TestBukketName, "renditions/720p.mp4"); err != nil || len(tags) != 0 {
+		t.Errorf("GetObjectTagging after DeleteObjectTagging returned %v, %v", tags, err)
+	}
+
+	if err = c.PutObjectTagging(TestBukketName, "missing.mp4", want); err == nil {
+		t.Errorf("PutObjectTagging of a missing object should fail")
+	}
+}
+
+func TestObjectTagSetValidate(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i <= MaxObjectTags; i++ {
+		tooMany[fmt.Sprint("k", i)] = "v"
+	}
+	for name, set := range map[string]ObjectTagSet{
+		"empty":          {},
+		"too many":       NewObjectTagSet(tooMany),
+		"empty key":      {{Key: "", Value: "v"}},
+		"long key":       {{Key: strings.Repeat("k", MaxTagKeyLength+1)}},
+		"long value":     {{Key: "k", Value: strings.Repeat("v", MaxTagValueLength+1)}},
+		"duplicate keys": {{Key: "k", Value: "1"}, {Key: "k", Value: "2"}},
+	} {
+		if err := set.Validate(); err == nil {
+			t.Errorf("Validate accepted a tag set with %s", name)
+		}
+	}
+	if err := NewObjectTagSet(map[string]string{"k": ""}).Validate(); err != nil {
+		t.Errorf("Validate rejected an empty value. %v", err)
+	}
+}
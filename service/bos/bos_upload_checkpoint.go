@@ -0,0 +1,276 @@
+package bos
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+// CheckpointSuffix is appended to the source file name to name its checkpoint file
+// when the caller does not choose one.
+const CheckpointSuffix = ".bos-checkpoint"
+
+// fingerprintSampleSize is how much of each end of a file goes into its fingerprint.
+const fingerprintSampleSize = 1024 * 1024
+
+// fileFingerprint identifies the exact version of a local file. Hashing a whole master
+// would cost as much as uploading it, so Hash covers only its first and last MiB; with
+// the size and modification time that is enough to notice a re-rendered file.
+type fileFingerprint struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Hash    string    `json:"hash"`
+}
+
+func fingerprintFile(file *os.File) (fileFingerprint, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return fileFingerprint{}, err
+	}
+
+	h := md5.New()
+	head := info.Size()
+	if head > fingerprintSampleSize {
+		head = fingerprintSampleSize
+	}
+	if _, err = io.Copy(h, io.NewSectionReader(file, 0, head)); err != nil {
+		return fileFingerprint{}, err
+	}
+	if tail := info.Size() - fingerprintSampleSize; tail > head {
+		if _, err = io.Copy(h, io.NewSectionReader(file, tail, fingerprintSampleSize)); err != nil {
+			return fileFingerprint{}, err
+		}
+	}
+
+	return fileFingerprint{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UTC(),
+		Hash:    fmt.Sprintf("%x", h.Sum(nil)),
+	}, nil
+}
+
+func (f fileFingerprint) equal(o fileFingerprint) bool {
+	return f.Size == o.Size && f.ModTime.Equal(o.ModTime) && f.Hash == o.Hash
+}
+
+// uploadCheckpoint is the on-disk state of a resumable upload.
+type uploadCheckpoint struct {
+	BucketName string          `json:"bucket"`
+	ObjectName string          `json:"key"`
+	UploadId   string          `json:"uploadId"`
+	File       fileFingerprint `json:"file"`
+	PartSize   int64           `json:"partSize"`
+	Parts      []PartInfo      `json:"parts"`
+}
+
+// loadCheckpoint decodes the checkpoint file at path into v. A missing file is not an
+// error; ok reports whether anything was loaded.
+func loadCheckpoint(path string, v interface{}) (ok bool, err error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		// A torn or foreign file is as good as none.
+		return false, nil
+	}
+	return true, nil
+}
+
+// saveCheckpoint atomically replaces the checkpoint file at path with v.
+func saveCheckpoint(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ResumableUploadFile uploads the named local file like UploadFile, but records its
+// progress in checkpointFile (fileName+CheckpointSuffix when empty). If the upload is
+// interrupted, calling it again with the same arguments uploads only the parts BOS does
+// not have yet. A checkpoint left by a different version of the file is discarded and
+// its upload aborted. The checkpoint is removed once the object is complete.
+func (u *Uploader) ResumableUploadFile(bucketName, objectName, fileName, checkpointFile string,
+	opts *PutObjectOptions) (*UploadResult, error) {
+
+	if opts == nil {
+		opts = &PutObjectOptions{}
+	}
+	if checkpointFile == "" {
+		checkpointFile = fileName + CheckpointSuffix
+	}
+	objectName = u.Client.formatPath(objectName)
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fingerprint, err := fingerprintFile(file)
+	if err != nil {
+		return nil, err
+	}
+	r := io.NewSectionReader(file, 0, fingerprint.Size)
+
+	partSize, err := u.partSize(fingerprint.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &uploadCheckpoint{}
+	loaded, err := loadCheckpoint(checkpointFile, cp)
+	if err != nil {
+		return nil, err
+	}
+	if loaded && (cp.BucketName != bucketName || cp.ObjectName != objectName ||
+		!cp.File.equal(fingerprint) || cp.PartSize <= 0) {
+
+		if cp.UploadId != "" {
+			u.Client.AbortMultipartUpload(cp.BucketName, cp.ObjectName, cp.UploadId)
+		}
+		loaded = false
+	}
+
+	if !loaded {
+		os.Remove(checkpointFile)
+		if fingerprint.Size < partSize {
+			return u.putSmall(bucketName, objectName, r, fingerprint.Size, opts)
+		}
+		cp = &uploadCheckpoint{
+			BucketName: bucketName,
+			ObjectName: objectName,
+			File:       fingerprint,
+			PartSize:   partSize,
+		}
+	}
+
+	done, err := u.reconcileCheckpoint(cp, r)
+	if err != nil {
+		return nil, err
+	}
+	if cp.UploadId == "" {
+		head := make([]byte, sniffLen)
+		n, _ := r.ReadAt(head, 0)
+		if cp.UploadId, err = u.initiate(bucketName, objectName, head[:n], opts); err != nil {
+			return nil, err
+		}
+	}
+	if err = saveCheckpoint(checkpointFile, cp); err != nil {
+		return nil, err
+	}
+
+	m := u.newMultipartUpload(context.Background(), bucketName, objectName, cp.UploadId)
+	m.keepOnError = true
+	m.sse = opts.Encryption
+	m.parts = append(m.parts, cp.Parts...)
+
+	var saveMu sync.Mutex
+	m.partDone = func(part PartInfo) {
+		saveMu.Lock()
+		defer saveMu.Unlock()
+		cp.Parts = append(cp.Parts, part)
+		if err := saveCheckpoint(checkpointFile, cp); err != nil {
+			m.fail(err)
+		}
+	}
+
+	res, err := m.run(u.readerAtParts(r, cp.PartSize, done), fingerprint.Size)
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(checkpointFile)
+	return res, nil
+}
+
+// reconcileCheckpoint brings cp.Parts in line with the parts BOS actually holds for the
+// upload, and returns the set of part numbers that need not be sent again. Parts BOS
+// has but the checkpoint missed are kept if they match the file. If the upload no longer
+// exists, cp is reset so that a new one is started.
+func (u *Uploader) reconcileCheckpoint(cp *uploadCheckpoint, r *io.SectionReader) (map[int]bool, error) {
+	done := map[int]bool{}
+	if cp.UploadId == "" {
+		cp.Parts = nil
+		return done, nil
+	}
+
+	remote, err := u.listAllParts(cp.BucketName, cp.ObjectName, cp.UploadId)
+	if err != nil {
+		if e, ok := err.(*httplib.ErrorResponse); ok && e.Code == "NoSuchUpload" {
+			cp.UploadId = ""
+			cp.Parts = nil
+			return done, nil
+		}
+		return nil, err
+	}
+
+	recorded := map[int]string{}
+	for _, p := range cp.Parts {
+		recorded[p.PartNumber] = p.ETag
+	}
+
+	parts := []PartInfo{}
+	for _, p := range remote {
+		offset := int64(p.PartNumber-1) * cp.PartSize
+		size := r.Size() - offset
+		if size > cp.PartSize {
+			size = cp.PartSize
+		}
+		if offset >= r.Size() || int64(p.Size) != size {
+			continue
+		}
+
+		eTag, ok := recorded[p.PartNumber]
+		if !ok {
+			h := md5.New()
+			if _, err := io.Copy(h, io.NewSectionReader(r, offset, size)); err != nil {
+				return nil, err
+			}
+			eTag = fmt.Sprintf("%x", h.Sum(nil))
+		}
+		if !strings.EqualFold(eTag, p.ETag) {
+			continue
+		}
+		done[p.PartNumber] = true
+		parts = append(parts, PartInfo{PartNumber: p.PartNumber, ETag: p.ETag, Size: p.Size})
+	}
+	cp.Parts = parts
+	return done, nil
+}
+
+// listAllParts returns every part uploaded so far.
+func (u *Uploader) listAllParts(bucketName, objectName, uploadId string) ([]PartInfo, error) {
+	var parts []PartInfo
+	it := u.Client.NewPartIterator(context.Background(), bucketName, objectName, uploadId, nil, 0)
+	for it.Next() {
+		parts = append(parts, it.Part())
+	}
+	return parts, it.Err()
+}
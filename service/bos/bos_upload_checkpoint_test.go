@@ -0,0 +1,139 @@
+package bos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// partUploads counts the UploadPart requests f has seen so far.
+func partUploads(f *fakeBos) map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	counts := map[string]int{}
+	for _, r := range f.requests {
+		if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") != "" {
+			counts[r.URL.Query().Get("partNumber")]++
+		}
+	}
+	return counts
+}
+
+func TestResumableUploadFile(t *testing.T) {
+	f, c := newFakeBos(t)
+	u := NewUploader(c)
+	u.PartSize = MinPartSize
+	u.Concurrency = 1
+	u.MaxRetries = 0
+
+	content := randomContent(t, MinPartSize*3+1)
+	fileName := filepath.Join(t.TempDir(), "field.mp4")
+	if err := ioutil.WriteFile(fileName, content, 0644); err != nil {
+		t.Fatalf("Write TestFile failed. %v", err)
+	}
+	checkpointFile := fileName + CheckpointSuffix
+
+	// Drop the connection on the third part.
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "3" {
+			f.fail(w, http.StatusInternalServerError, "InternalError", "link down")
+			return true
+		}
+		return false
+	}
+	if _, err := u.ResumableUploadFile(TestBukketName, "field.mp4", fileName, "", nil); err == nil {
+		t.Fatalf("ResumableUploadFile should fail.")
+	}
+	if len(f.uploads) != 1 {
+		t.Fatalf("ResumableUploadFile failed. upload should be kept for resuming")
+	}
+
+	cp := &uploadCheckpoint{}
+	if ok, err := loadCheckpoint(checkpointFile, cp); !ok || err != nil {
+		t.Fatalf("ResumableUploadFile failed. checkpoint not written. %v", err)
+	}
+	if len(cp.Parts) != 2 {
+		t.Fatalf("ResumableUploadFile failed. checkpoint has %d parts, want 2", len(cp.Parts))
+	}
+
+	// Forget part 2 as if the process died before saving it; BOS still has it.
+	cp.Parts = cp.Parts[:1]
+	if err := saveCheckpoint(checkpointFile, cp); err != nil {
+		t.Fatalf("saveCheckpoint failed. %v", err)
+	}
+
+	f.intercept = nil
+	before := partUploads(f)
+	res, err := u.ResumableUploadFile(TestBukketName, "field.mp4", fileName, "", nil)
+	if err != nil {
+		t.Fatalf("ResumableUploadFile failed. %v", err)
+	}
+	after := partUploads(f)
+	for _, n := range []string{"1", "2"} {
+		if after[n] != before[n] {
+			t.Errorf("ResumableUploadFile failed. part %s uploaded again", n)
+		}
+	}
+	for _, n := range []string{"3", "4"} {
+		if after[n] != before[n]+1 {
+			t.Errorf("ResumableUploadFile failed. part %s not uploaded once", n)
+		}
+	}
+	if res.UploadId != cp.UploadId {
+		t.Errorf("ResumableUploadFile failed. upload id changed")
+	}
+	if obj := f.object(TestBukketName, "field.mp4"); obj == nil || !bytes.Equal(obj.data, content) {
+		t.Errorf("ResumableUploadFile failed. Content Not Match.")
+	}
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Errorf("ResumableUploadFile failed. checkpoint not removed")
+	}
+}
+
+func TestResumableUploadFileStaleCheckpoint(t *testing.T) {
+	f, c := newFakeBos(t)
+	u := NewUploader(c)
+	u.PartSize = MinPartSize
+	u.Concurrency = 1
+	u.MaxRetries = 0
+
+	content := randomContent(t, MinPartSize*2)
+	fileName := filepath.Join(t.TempDir(), "render.mov")
+	if err := ioutil.WriteFile(fileName, content, 0644); err != nil {
+		t.Fatalf("Write TestFile failed. %v", err)
+	}
+
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "2" {
+			f.fail(w, http.StatusInternalServerError, "InternalError", "link down")
+			return true
+		}
+		return false
+	}
+	if _, err := u.ResumableUploadFile(TestBukketName, "render.mov", fileName, "", nil); err == nil {
+		t.Fatalf("ResumableUploadFile should fail.")
+	}
+	f.intercept = nil
+
+	// Re-render the file: the old upload must be thrown away.
+	content = randomContent(t, MinPartSize*2)
+	if err := ioutil.WriteFile(fileName, content, 0644); err != nil {
+		t.Fatalf("Write TestFile failed. %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(fileName, later, later)
+
+	if _, err := u.ResumableUploadFile(TestBukketName, "render.mov", fileName, "", nil); err != nil {
+		t.Fatalf("ResumableUploadFile failed. %v", err)
+	}
+	if obj := f.object(TestBukketName, "render.mov"); obj == nil || !bytes.Equal(obj.data, content) {
+		t.Errorf("ResumableUploadFile failed. Content Not Match.")
+	}
+	if len(f.uploads) != 0 {
+		t.Errorf("ResumableUploadFile failed. stale upload not aborted")
+	}
+}
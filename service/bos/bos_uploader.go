@@ -0,0 +1,474 @@
+package bos
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Multipart limits imposed by BOS.
+const (
+	MinPartSize = 5 * 1024 * 1024
+	MaxPartSize = 5 * 1024 * 1024 * 1024
+	MaxParts    = 10000
+)
+
+const (
+	DefaultPartSize          = 10 * 1024 * 1024
+	DefaultUploadConcurrency = 5
+	DefaultPartRetries       = 3
+)
+
+// Uploader uploads large objects to BOS, splitting them into parts that are sent
+// concurrently. Inputs smaller than a single part are sent with one PutObject.
+type Uploader struct {
+	Client *BosClient
+
+	// PartSize is the preferred part size. It is raised when the input would otherwise
+	// need more than MaxParts parts.
+	PartSize int64
+
+	// Concurrency is the number of parts uploaded at the same time.
+	Concurrency int
+
+	// MaxRetries is how many times a failed part is retried before the whole upload is
+	// aborted.
+	MaxRetries int
+
+	// singleCopyLimit overrides MaxSingleCopySize for CopyLargeObject, so that tests
+	// can exercise multipart copies without 5 GiB objects.
+	singleCopyLimit int64
+}
+
+type UploadResult struct {
+	BucketName string
+	ObjectName string
+	ETag       string
+
+	// UploadId is empty when the object was sent with a single PutObject.
+	UploadId string
+	Size     int64
+}
+
+func NewUploader(c *BosClient) *Uploader {
+	return &Uploader{
+		Client:      c,
+		PartSize:    DefaultPartSize,
+		Concurrency: DefaultUploadConcurrency,
+		MaxRetries:  DefaultPartRetries,
+	}
+}
+
+// UploadFile uploads the named local file to bucketName/objectName.
+func (u *Uploader) UploadFile(bucketName, objectName, fileName string, opts *PutObjectOptions) (*UploadResult, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return u.Upload(bucketName, objectName, file, opts)
+}
+
+// Upload uploads everything read from body to bucketName/objectName. Parts are read
+// concurrently when body is an io.ReaderAt of known size, such as an *os.File;
+// otherwise body is read sequentially and at most Concurrency+1 parts are buffered:
+// one per part being sent, and the next one read.
+func (u *Uploader) Upload(bucketName, objectName string, body io.Reader, opts *PutObjectOptions) (*UploadResult, error) {
+	return u.upload(context.Background(), bucketName, objectName, body, opts)
+}
+
+func (u *Uploader) upload(ctx context.Context, bucketName, objectName string, body io.Reader,
+	opts *PutObjectOptions) (*UploadResult, error) {
+
+	if opts == nil {
+		opts = &PutObjectOptions{}
+	}
+
+	if ra, ok := body.(io.ReaderAt); ok {
+		if size, ok := readerLength(body); ok {
+			offset := int64(0)
+			if s, ok := body.(io.Seeker); ok {
+				offset, _ = s.Seek(0, io.SeekCurrent)
+			}
+			return u.uploadReaderAt(ctx, bucketName, objectName, io.NewSectionReader(ra, offset, size), opts)
+		}
+	}
+	return u.uploadStream(ctx, bucketName, objectName, body, opts)
+}
+
+// partSize picks the part size for an input of the given size, or of unknown size
+// when size is negative.
+func (u *Uploader) partSize(size int64) (int64, error) {
+	partSize := u.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	if partSize < MinPartSize {
+		partSize = MinPartSize
+	}
+	if size > 0 && (size+partSize-1)/partSize > MaxParts {
+		// Round up to a whole MiB so the parts stay tidy.
+		partSize = (size + MaxParts - 1) / MaxParts
+		partSize = (partSize + 1024*1024 - 1) / (1024 * 1024) * 1024 * 1024
+	}
+	if partSize > MaxPartSize {
+		return 0, fmt.Errorf("object of %d bytes exceeds the BOS limit of %d parts of %d bytes",
+			size, MaxParts, int64(MaxPartSize))
+	}
+	return partSize, nil
+}
+
+// uploadPartJob is a part waiting to be sent. Exactly one of data, section and copy is
+// set.
+type uploadPartJob struct {
+	number  int
+	size    int64
+	data    []byte
+	section *io.SectionReader
+	copy    *partCopySource
+}
+
+func (j *uploadPartJob) reader() io.Reader {
+	if j.data != nil {
+		return bytes.NewReader(j.data)
+	}
+	return io.NewSectionReader(j.section, 0, j.size)
+}
+
+// multipartUpload tracks an in-flight multipart upload on behalf of the workers.
+type multipartUpload struct {
+	uploader   *Uploader
+	bucketName string
+	objectName string
+	uploadId   string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	parts []PartInfo
+	err   error
+
+	// partDone, when set, is called after each part is uploaded.
+	partDone func(part PartInfo)
+
+	// keepOnError leaves a failed upload in place, for a later resume, instead of
+	// aborting it.
+	keepOnError bool
+
+	// sse is the server-side encryption the upload was initiated with.
+	sse *ServerSideEncryption
+}
+
+func (u *Uploader) newMultipartUpload(ctx context.Context, bucketName, objectName, uploadId string) *multipartUpload {
+	ctx, cancel := context.WithCancel(ctx)
+	return &multipartUpload{
+		uploader:   u,
+		bucketName: bucketName,
+		objectName: objectName,
+		uploadId:   uploadId,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+func (m *multipartUpload) fail(err error) {
+	m.mu.Lock()
+	if m.err == nil {
+		m.err = err
+	}
+	m.mu.Unlock()
+	m.cancel()
+}
+
+func (m *multipartUpload) failed() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// worker uploads parts from jobs until the channel is closed.
+func (m *multipartUpload) worker(wg *sync.WaitGroup, jobs <-chan *uploadPartJob) {
+	defer wg.Done()
+	for job := range jobs {
+		if m.ctx.Err() != nil {
+			continue
+		}
+		eTag, err := m.uploadPart(job)
+		if err != nil {
+			m.fail(fmt.Errorf("upload part %d: %v", job.number, err))
+			continue
+		}
+		part := PartInfo{PartNumber: job.number, ETag: eTag, Size: int(job.size)}
+		m.mu.Lock()
+		m.parts = append(m.parts, part)
+		m.mu.Unlock()
+		if m.partDone != nil {
+			m.partDone(part)
+		}
+	}
+}
+
+// uploadPart sends one part, retrying on failure, and checks the returned ETag against
+// the MD5 of what was sent where BOS makes that possible. Parts copied from another
+// object are only retried.
+func (m *multipartUpload) uploadPart(job *uploadPartJob) (eTag string, err error) {
+	for attempt := 0; attempt <= m.uploader.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-m.ctx.Done():
+				return "", m.ctx.Err()
+			case <-time.After(time.Duration(attempt*attempt) * 200 * time.Millisecond):
+			}
+		}
+
+		if job.copy != nil {
+			eTag, err = m.copyPart(job)
+			if err == nil || IsPreconditionFailed(err) {
+				// A source that no longer matches will not match on a retry either.
+				return eTag, err
+			}
+			continue
+		}
+
+		h := md5.New()
+		eTag, err = m.uploader.Client.UploadPartWithOptions(m.bucketName, m.objectName, m.uploadId,
+			job.number, io.TeeReader(job.reader(), h), &UploadPartOptions{ContentLength: job.size, Encryption: m.sse})
+		if err != nil {
+			continue
+		}
+		if !m.sse.verifiableETag() {
+			return eTag, nil
+		}
+		if sum := fmt.Sprintf("%x", h.Sum(nil)); !strings.EqualFold(eTag, sum) {
+			err = fmt.Errorf("eTag %q does not match content MD5 %q", eTag, sum)
+			continue
+		}
+		return eTag, nil
+	}
+	return "", err
+}
+
+// abort gives up on the upload, unless it is to be kept for resuming.
+func (m *multipartUpload) abort() {
+	if !m.keepOnError {
+		m.uploader.Client.AbortMultipartUpload(m.bucketName, m.objectName, m.uploadId)
+	}
+}
+
+// complete finishes the upload, or aborts it if any part failed.
+func (m *multipartUpload) complete(size int64) (*UploadResult, error) {
+	defer m.cancel()
+
+	if err := m.failed(); err != nil {
+		m.abort()
+		return nil, err
+	}
+	if err := m.ctx.Err(); err != nil {
+		m.abort()
+		return nil, err
+	}
+
+	sort.Slice(m.parts, func(i, j int) bool { return m.parts[i].PartNumber < m.parts[j].PartNumber })
+	parts := make([]PartInfo, len(m.parts))
+	for i, p := range m.parts {
+		parts[i] = PartInfo{PartNumber: p.PartNumber, ETag: p.ETag}
+	}
+
+	res, err := m.uploader.Client.CompleteMultipartUpload(m.bucketName, m.objectName, m.uploadId, parts)
+	if err != nil {
+		m.abort()
+		return nil, err
+	}
+	return &UploadResult{
+		BucketName: m.bucketName,
+		ObjectName: m.objectName,
+		ETag:       strings.Trim(res.ETag, "\""),
+		UploadId:   m.uploadId,
+		Size:       size,
+	}, nil
+}
+
+func (u *Uploader) concurrency() int {
+	if u.Concurrency <= 0 {
+		return 1
+	}
+	return u.Concurrency
+}
+
+// putSmall uploads body with a single PutObject.
+func (u *Uploader) putSmall(bucketName, objectName string, body io.Reader, size int64,
+	opts *PutObjectOptions) (*UploadResult, error) {
+
+	o := *opts
+	o.ContentLength = size
+	eTag, err := u.Client.PutObjectWithOptions(bucketName, objectName, body, &o)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{
+		BucketName: bucketName,
+		ObjectName: u.Client.formatPath(objectName),
+		ETag:       eTag,
+		Size:       size,
+	}, nil
+}
+
+// initiate starts the multipart upload, filling in the content type from head when
+// neither opts nor the object name provide one.
+func (u *Uploader) initiate(bucketName, objectName string, head []byte, opts *PutObjectOptions) (string, error) {
+	o := *opts
+	if o.ContentType == "" {
+		o.ContentType, _ = detectContentType(objectName, bytes.NewReader(head))
+	}
+	res, err := u.Client.InitiateMultipartUploadWithOptions(bucketName, objectName, &o)
+	if err != nil {
+		return "", err
+	}
+	return res.UploadId, nil
+}
+
+func (u *Uploader) uploadReaderAt(ctx context.Context, bucketName, objectName string, r *io.SectionReader,
+	opts *PutObjectOptions) (*UploadResult, error) {
+
+	size := r.Size()
+	partSize, err := u.partSize(size)
+	if err != nil {
+		return nil, err
+	}
+	if size < partSize {
+		return u.putSmall(bucketName, objectName, r, size, opts)
+	}
+
+	head := make([]byte, sniffLen)
+	n, _ := r.ReadAt(head, 0)
+	uploadId, err := u.initiate(bucketName, objectName, head[:n], opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m := u.newMultipartUpload(ctx, bucketName, u.Client.formatPath(objectName), uploadId)
+	m.sse = opts.Encryption
+	return m.run(u.readerAtParts(r, partSize, nil), size)
+}
+
+// readerAtParts lists the parts of r, leaving out the part numbers in skip.
+func (u *Uploader) readerAtParts(r *io.SectionReader, partSize int64, skip map[int]bool) []*uploadPartJob {
+	var jobs []*uploadPartJob
+	size := r.Size()
+	for number, offset := 1, int64(0); offset < size; number, offset = number+1, offset+partSize {
+		n := partSize
+		if size-offset < n {
+			n = size - offset
+		}
+		if skip[number] {
+			continue
+		}
+		jobs = append(jobs, &uploadPartJob{
+			number:  number,
+			size:    n,
+			section: io.NewSectionReader(r, offset, n),
+		})
+	}
+	return jobs
+}
+
+// run uploads the given parts with the worker pool and completes the upload.
+func (m *multipartUpload) run(parts []*uploadPartJob, size int64) (*UploadResult, error) {
+	var wg sync.WaitGroup
+	jobs := make(chan *uploadPartJob)
+	for i := 0; i < m.uploader.concurrency(); i++ {
+		wg.Add(1)
+		go m.worker(&wg, jobs)
+	}
+
+	for _, job := range parts {
+		if m.ctx.Err() != nil {
+			break
+		}
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+
+	return m.complete(size)
+}
+
+func (u *Uploader) uploadStream(ctx context.Context, bucketName, objectName string, body io.Reader,
+	opts *PutObjectOptions) (*UploadResult, error) {
+
+	partSize, err := u.partSize(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := readPart(body, partSize)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(first)) < partSize {
+		return u.putSmall(bucketName, objectName, bytes.NewReader(first), int64(len(first)), opts)
+	}
+
+	uploadId, err := u.initiate(bucketName, objectName, first, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m := u.newMultipartUpload(ctx, bucketName, u.Client.formatPath(objectName), uploadId)
+	m.sse = opts.Encryption
+
+	var wg sync.WaitGroup
+	// Unbuffered, so that no more than one part waits beyond those being sent.
+	jobs := make(chan *uploadPartJob)
+	for i := 0; i < m.uploader.concurrency(); i++ {
+		wg.Add(1)
+		go m.worker(&wg, jobs)
+	}
+
+	size := int64(0)
+	data := first
+	for number := 1; len(data) > 0; number++ {
+		if number > MaxParts {
+			m.fail(fmt.Errorf("stream exceeds %d parts of %d bytes, set a larger PartSize", MaxParts, partSize))
+			break
+		}
+		size += int64(len(data))
+		select {
+		case jobs <- &uploadPartJob{number: number, size: int64(len(data)), data: data}:
+		case <-m.ctx.Done():
+		}
+		if m.ctx.Err() != nil {
+			break
+		}
+		if int64(len(data)) < partSize {
+			break
+		}
+		if data, err = readPart(body, partSize); err != nil {
+			m.fail(err)
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return m.complete(size)
+}
+
+// readPart reads up to partSize bytes from r. A short result means r is exhausted.
+func readPart(r io.Reader, partSize int64) ([]byte, error) {
+	buf := make([]byte, partSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
@@ -0,0 +1,163 @@
+package bos
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func randomContent(t *testing.T, size int) []byte {
+	content := make([]byte, size)
+	if _, err := rand.Read(content); err != nil {
+		t.Fatalf("rand.Read failed. %v", err)
+	}
+	return content
+}
+
+func TestUploaderSmallObject(t *testing.T) {
+	f, c := newFakeBos(t)
+	u := NewUploader(c)
+
+	content := randomContent(t, 1024)
+	res, err := u.Upload(TestBukketName, "small.ts", bytes.NewReader(content), nil)
+	if err != nil {
+		t.Fatalf("Upload failed. %v", err)
+	}
+	if res.UploadId != "" {
+		t.Errorf("Upload failed. small object should not use multipart")
+	}
+	obj := f.object(TestBukketName, "small.ts")
+	if obj == nil || !bytes.Equal(obj.data, content) || res.ETag != obj.eTag {
+		t.Errorf("Upload failed. Content Not Match.")
+	}
+	if got := obj.header.Get("Content-Type"); got != "video/mp2t" {
+		t.Errorf("Upload failed. Content-Type = %q", got)
+	}
+}
+
+func TestUploaderUploadFile(t *testing.T) {
+	f, c := newFakeBos(t)
+	u := NewUploader(c)
+	u.PartSize = MinPartSize
+	u.Concurrency = 3
+
+	content := randomContent(t, MinPartSize*2+123)
+	fileName := filepath.Join(t.TempDir(), "master.mov")
+	if err := ioutil.WriteFile(fileName, content, 0644); err != nil {
+		t.Fatalf("Write TestFile failed. %v", err)
+	}
+
+	res, err := u.UploadFile(TestBukketName, "masters/master.mov", fileName,
+		&PutObjectOptions{UserMeta: map[string]string{"title": "master"}})
+	if err != nil {
+		t.Fatalf("UploadFile failed. %v", err)
+	}
+	if res.UploadId == "" || res.Size != int64(len(content)) {
+		t.Errorf("UploadFile failed. result = %+v", res)
+	}
+	obj := f.object(TestBukketName, "masters/master.mov")
+	if obj == nil || !bytes.Equal(obj.data, content) {
+		t.Fatalf("UploadFile failed. Content Not Match.")
+	}
+	if got := obj.header.Get("Content-Type"); got != "video/quicktime" {
+		t.Errorf("UploadFile failed. Content-Type = %q", got)
+	}
+	if got := obj.header.Get("X-Bce-Meta-Title"); got != "master" {
+		t.Errorf("UploadFile failed. user meta = %q", got)
+	}
+}
+
+func TestUploaderStream(t *testing.T) {
+	f, c := newFakeBos(t)
+	u := NewUploader(c)
+	u.PartSize = MinPartSize
+
+	content := randomContent(t, MinPartSize*2+MinPartSize/2)
+	res, err := u.Upload(TestBukketName, "stream", ioutil.NopCloser(bytes.NewReader(content)), nil)
+	if err != nil {
+		t.Fatalf("Upload failed. %v", err)
+	}
+	if res.UploadId == "" {
+		t.Errorf("Upload failed. stream should use multipart")
+	}
+	obj := f.object(TestBukketName, "stream")
+	if obj == nil || !bytes.Equal(obj.data, content) {
+		t.Fatalf("Upload failed. Content Not Match.")
+	}
+}
+
+func TestUploaderRetry(t *testing.T) {
+	f, c := newFakeBos(t)
+	u := NewUploader(c)
+	u.PartSize = MinPartSize
+
+	var mu sync.Mutex
+	failures := 0
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "2" && failures < 2 {
+			failures++
+			f.fail(w, http.StatusInternalServerError, "InternalError", "try again")
+			return true
+		}
+		return false
+	}
+
+	content := randomContent(t, MinPartSize*2)
+	_, err := u.Upload(TestBukketName, "retry", bytes.NewReader(content), nil)
+	if err != nil {
+		t.Fatalf("Upload failed. %v", err)
+	}
+	if failures != 2 {
+		t.Errorf("Upload failed. %d injected failures, want 2", failures)
+	}
+	if obj := f.object(TestBukketName, "retry"); obj == nil || !bytes.Equal(obj.data, content) {
+		t.Errorf("Upload failed. Content Not Match.")
+	}
+}
+
+func TestUploaderAbort(t *testing.T) {
+	f, c := newFakeBos(t)
+	u := NewUploader(c)
+	u.PartSize = MinPartSize
+	u.MaxRetries = 1
+
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "2" {
+			f.fail(w, http.StatusInternalServerError, "InternalError", "broken")
+			return true
+		}
+		return false
+	}
+
+	content := randomContent(t, MinPartSize*3)
+	if _, err := u.Upload(TestBukketName, "abort", bytes.NewReader(content), nil); err == nil {
+		t.Fatalf("Upload should fail.")
+	}
+	if req := f.lastRequest(http.MethodDelete); req == nil || req.URL.Query().Get("uploadId") == "" {
+		t.Errorf("Upload failed. AbortMultipartUpload not called")
+	}
+	if len(f.uploads) != 0 || f.object(TestBukketName, "abort") != nil {
+		t.Errorf("Upload failed. upload left behind")
+	}
+}
+
+func TestUploaderPartSize(t *testing.T) {
+	u := NewUploader(nil)
+
+	partSize, err := u.partSize(int64(MaxParts) * DefaultPartSize * 3)
+	if err != nil {
+		t.Fatalf("partSize failed. %v", err)
+	}
+	if partSize != DefaultPartSize*3 {
+		t.Errorf("partSize = %d, want %d", partSize, DefaultPartSize*3)
+	}
+	if _, err = u.partSize(int64(MaxParts)*MaxPartSize + 1); err == nil {
+		t.Errorf("partSize should reject objects above the BOS limit")
+	}
+}
@@ -0,0 +1,100 @@
+package bos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spiderorg/bd-video-sdk/httplib"
+)
+
+// BucketWebsite has a bucket serve a static site, such as player pages. Index is the
+// document served for a request ending in "/", and NotFound the one served, with a 404,
+// for a missing object.
+type BucketWebsite struct {
+	Index    string `json:"index,omitempty"`
+	NotFound string `json:"notFound,omitempty"`
+}
+
+/*
+ * Name: PutBucketStaticWebsite
+ * URL: http://bce.baidu.com/doc/BOS/API.html#PutBucketStaticWebsite.E6.8E.A5.E5.8F.A3
+ */
+
+// PutBucketStaticWebsite replaces the static website configuration of a bucket. At
+// least one of the documents must be set, and Index must be a plain object name.
+func (c *BosClient) PutBucketStaticWebsite(bucketName string, website *BucketWebsite) (err error) {
+	if website == nil || (website.Index == "" && website.NotFound == "") {
+		return fmt.Errorf("static website configuration needs an index or a not-found document")
+	}
+	if strings.Contains(website.Index, "/") {
+		return fmt.Errorf("index document %q must not contain a slash", website.Index)
+	}
+	doc := *website
+	doc.NotFound = c.formatPath(doc.NotFound)
+	jstring, err := json.Marshal(doc)
+	if err != nil {
+		return
+	}
+
+	req := &httplib.Request{
+		Method:  httplib.PUT,
+		Headers: map[string]string{},
+		Query:   "website",
+		Path:    c.APIVersion + "/" + bucketName,
+		Body:    bytes.NewReader(jstring),
+		Type:    httplib.JSON,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
+
+/*
+ * Name: GetBucketStaticWebsite
+ * URL: http://bce.baidu.com/doc/BOS/API.html#GetBucketStaticWebsite.E6.8E.A5.E5.8F.A3
+ */
+
+func (c *BosClient) GetBucketStaticWebsite(bucketName string) (output *BucketWebsite, err error) {
+	req := &httplib.Request{
+		Method:  httplib.GET,
+		Headers: map[string]string{},
+		Query:   "website",
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	res, err := c.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response BucketWebsite
+
+	j := json.NewDecoder(strings.NewReader(string(body)))
+	j.Decode(&response)
+	return &response, nil
+}
+
+/*
+ * Name: DeleteBucketStaticWebsite
+ * URL: http://bce.baidu.com/doc/BOS/API.html#DeleteBucketStaticWebsite.E6.8E.A5.E5.8F.A3
+ */
+
+func (c *BosClient) DeleteBucketStaticWebsite(bucketName string) (err error) {
+	req := &httplib.Request{
+		Method:  httplib.DELETE,
+		Headers: map[string]string{},
+		Query:   "website",
+		Path:    c.APIVersion + "/" + bucketName,
+	}
+
+	_, err = c.DoRequest(req)
+	return
+}
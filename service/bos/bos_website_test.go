@@ -0,0 +1,32 @@
+package bos
+
+import (
+	"testing"
+)
+
+func TestBucketStaticWebsite(t *testing.T) {
+	_, c := newFakeBos(t)
+
+	if _, err := c.GetBucketStaticWebsite(TestBukketName); err == nil {
+		t.Errorf("GetBucketStaticWebsite should fail before the website is set")
+	}
+	if err := c.PutBucketStaticWebsite(TestBukketName, &BucketWebsite{Index: "index.html", NotFound: "/errors/404.html"}); err != nil {
+		t.Fatalf("PutBucketStaticWebsite failed. %v", err)
+	}
+	website, err := c.GetBucketStaticWebsite(TestBukketName)
+	if err != nil || website.Index != "index.html" || website.NotFound != "errors/404.html" {
+		t.Errorf("GetBucketStaticWebsite returned %+v, %v", website, err)
+	}
+	if err = c.DeleteBucketStaticWebsite(TestBukketName); err != nil {
+		t.Errorf("DeleteBucketStaticWebsite failed. %v", err)
+	}
+	if _, err = c.GetBucketStaticWebsite(TestBukketName); err == nil {
+		t.Errorf("GetBucketStaticWebsite should fail after the website is deleted")
+	}
+
+	for _, website := range []*BucketWebsite{nil, {}, {Index: "pages/index.html"}} {
+		if err = c.PutBucketStaticWebsite(TestBukketName, website); err == nil {
+			t.Errorf("PutBucketStaticWebsite accepted %+v", website)
+		}
+	}
+}